@@ -26,7 +26,9 @@ func init() {
 	var err error
 	cfg, err = config.GetConfig()
 	if err != nil {
+		// Commands which need the cluster fail once they use the clients
 		fmt.Println(err)
+		return
 	}
 	client, err = v1alpha1.NewForConfig(cfg)
 	if err != nil {
@@ -40,7 +42,9 @@ func init() {
 	if err != nil {
 		fmt.Println(err)
 	}
-	namespace = clientCfg.Contexts[clientCfg.CurrentContext].Namespace
+	if context, ok := clientCfg.Contexts[clientCfg.CurrentContext]; ok {
+		namespace = context.Namespace
+	}
 	if namespace == "" {
 		namespace = "default"
 	}
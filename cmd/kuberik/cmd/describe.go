@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/generated/clientset/versioned/typed/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/playtree"
+	"github.com/spf13/cobra"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// number of the most recent Events of a Play which are described
+const recentEvents = 10
+
+// icons of statuses of scenes and frames
+var statusIcons = map[playtree.Status]string{
+	playtree.StatusPending:   "○",
+	playtree.StatusRunning:   "●",
+	playtree.StatusSucceeded: "✔",
+	playtree.StatusFailed:    "✘",
+	playtree.StatusSkipped:   "⊘",
+}
+
+var describePlayOutput *string
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	describeCmd.AddCommand(describePlayCmd)
+	describePlayOutput = addOutputFlag(describePlayCmd)
+}
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Show details of Kuberik resources",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// describePlayCmd represents the describe play command
+var describePlayCmd = &cobra.Command{
+	Use:   "play <name>",
+	Short: "Show details of a Play",
+	Long: `Show the status of a Play with its screenplays, scenes and frames as a
+tree. Frames are listed with their status, duration, exit code and the
+number of attempts if they were retried, followed by recent Events of the Play.`,
+	Args: cobra.ExactArgs(1),
+	Run:  describePlay,
+}
+
+// playDescription is the description of a Play printed by describe
+type playDescription struct {
+	playtree.Tree
+	// Executions are the last executions of frames keyed by frame IDs
+	Executions map[string]frameExecution `json:"executions,omitempty"`
+	// Events are the most recent Events of the Play
+	Events []playEvent `json:"events,omitempty"`
+}
+
+// frameExecution is the last execution of a frame
+type frameExecution struct {
+	Job            string       `json:"job"`
+	Attempts       int          `json:"attempts"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// playEvent is an Event of a Play
+type playEvent struct {
+	Type          string      `json:"type"`
+	Reason        string      `json:"reason"`
+	Message       string      `json:"message"`
+	Count         int32       `json:"count,omitempty"`
+	LastTimestamp metav1.Time `json:"lastTimestamp"`
+}
+
+func describePlay(cmd *cobra.Command, args []string) {
+	if err := validateOutput(*describePlayOutput); err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	description, err := newPlayDescription(client, kubeClient, namespace, args[0])
+	if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	if err := printDescription(cmd.OutOrStdout(), description, *describePlayOutput, time.Now()); err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+}
+
+// newPlayDescription describes the Play from its status, its Jobs and its Events
+func newPlayDescription(kuberik v1alpha1.CoreV1alpha1Interface, kube kubeclient.Interface, namespace, name string) (*playDescription, error) {
+	play, err := kuberik.Plays(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	description := &playDescription{Tree: playtree.New(play), Executions: make(map[string]frameExecution)}
+
+	jobs, err := kube.BatchV1().Jobs(namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(kuberikRuntime.PlaySelector(play)).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	created := make(map[string]metav1.Time)
+	for _, job := range jobs.Items {
+		frame := job.Labels[kuberikRuntime.FrameLabel]
+		if last, ok := created[frame]; ok && job.CreationTimestamp.Before(&last) {
+			continue
+		}
+		created[frame] = job.CreationTimestamp
+		description.Executions[frame] = frameExecution{
+			Job:            job.Name,
+			Attempts:       jobAttempts(&job),
+			StartTime:      job.Status.StartTime,
+			CompletionTime: jobCompletionTime(&job),
+		}
+	}
+
+	events, err := kube.CoreV1().Events(namespace).List(metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("involvedObject.name=%s", play.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Kind != "Play" || (event.InvolvedObject.UID != "" && event.InvolvedObject.UID != play.UID) {
+			continue
+		}
+		description.Events = append(description.Events, playEvent{
+			Type:          event.Type,
+			Reason:        event.Reason,
+			Message:       event.Message,
+			Count:         event.Count,
+			LastTimestamp: eventTime(&event),
+		})
+	}
+	sort.SliceStable(description.Events, func(i, j int) bool {
+		return description.Events[i].LastTimestamp.Before(&description.Events[j].LastTimestamp)
+	})
+	if len(description.Events) > recentEvents {
+		description.Events = description.Events[len(description.Events)-recentEvents:]
+	}
+	return description, nil
+}
+
+// jobAttempts returns the number of attempts of the Job. Frames are retried
+// within their Job, so every Pod of the Job is an attempt.
+func jobAttempts(job *batchv1.Job) int {
+	return int(job.Status.Active + job.Status.Succeeded + job.Status.Failed)
+}
+
+// jobCompletionTime returns the time the Job finished. Failed Jobs don't have
+// a completion time, so the time of their failure is used.
+func jobCompletionTime(job *batchv1.Job) *metav1.Time {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return &condition.LastTransitionTime
+		}
+	}
+	return nil
+}
+
+func eventTime(event *corev1.Event) metav1.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp
+	case !event.EventTime.IsZero():
+		return metav1.NewTime(event.EventTime.Time)
+	}
+	return event.FirstTimestamp
+}
+
+// printDescription prints the description of the Play, or the description in JSON or YAML
+func printDescription(out io.Writer, description *playDescription, output string, now time.Time) error {
+	if output == outputJSON || output == outputYAML {
+		return printObject(out, output, description)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", description.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", description.Namespace)
+	fmt.Fprintf(w, "Movie:\t%s\n", orNone(description.Movie))
+	fmt.Fprintf(w, "Phase:\t%s\n", orNone(string(description.Phase)))
+	if description.Cancelled {
+		fmt.Fprintf(w, "Cancelled:\ttrue\n")
+	}
+	fmt.Fprintf(w, "Started:\t%s\n", timestamp(description.StartTime))
+	fmt.Fprintf(w, "Completed:\t%s\n", timestamp(description.CompletionTime))
+	fmt.Fprintf(w, "Duration:\t%s\n", elapsed(description.StartTime, description.CompletionTime, now))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(description.Conditions) > 0 {
+		fmt.Fprintln(out, "Conditions:")
+		w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "  TYPE\tSTATUS\tREASON\tMESSAGE")
+		for _, condition := range description.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out, "Screenplays:")
+	for _, screenplay := range description.Screenplays {
+		fmt.Fprintf(out, "  %s\n", screenplay.Name)
+		for i, scene := range screenplay.Scenes {
+			branch, indent := treeBranch(i, len(screenplay.Scenes))
+			line := fmt.Sprintf("%s %s", statusIcon(scene.Status), scene.Name)
+			if scene.IgnoreErrors {
+				line += "  (errors ignored)"
+			}
+			fmt.Fprintf(out, "  %s%s\n", branch, line)
+			for j, frame := range scene.Frames {
+				frameBranch, _ := treeBranch(j, len(scene.Frames))
+				fmt.Fprintf(out, "  %s%s%s\n", indent, frameBranch, describeFrame(frame, description.Executions[frame.ID], output == outputWide, now))
+			}
+		}
+	}
+
+	fmt.Fprintln(out, "Events:")
+	if len(description.Events) == 0 {
+		fmt.Fprintln(out, "  <none>")
+		return nil
+	}
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tREASON\tAGE\tMESSAGE")
+	for _, event := range description.Events {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", event.Type, event.Reason, age(event.LastTimestamp, now), event.Message)
+	}
+	return w.Flush()
+}
+
+// describeFrame returns the line of the frame in the tree of the Play
+func describeFrame(frame playtree.Frame, execution frameExecution, wide bool, now time.Time) string {
+	parts := []string{fmt.Sprintf("%s %s", statusIcon(frame.Status), frame.Name)}
+	if execution.StartTime != nil {
+		parts = append(parts, elapsed(execution.StartTime, execution.CompletionTime, now))
+	}
+	if frame.ExitCode != nil {
+		parts = append(parts, fmt.Sprintf("exit %d", *frame.ExitCode))
+	}
+	if frame.Status == playtree.StatusSkipped {
+		parts = append(parts, "skipped")
+	}
+	if execution.Attempts > 1 {
+		parts = append(parts, fmt.Sprintf("retried (%d attempts)", execution.Attempts))
+	}
+	if frame.Failure != nil {
+		failure := frame.Failure.Reason
+		if frame.Failure.Message != "" {
+			failure += ": " + frame.Failure.Message
+		}
+		parts = append(parts, failure)
+	}
+	if wide {
+		parts = append(parts, fmt.Sprintf("id=%s", frame.ID))
+		if execution.Job != "" {
+			parts = append(parts, fmt.Sprintf("job=%s", execution.Job))
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// treeBranch returns the branch of the i-th of n children in a tree and the
+// indentation of its own children
+func treeBranch(i, n int) (string, string) {
+	if i == n-1 {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
+
+func statusIcon(status playtree.Status) string {
+	if icon, ok := statusIcons[status]; ok {
+		return icon
+	}
+	return "?"
+}
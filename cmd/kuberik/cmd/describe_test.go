@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/enginetest"
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func frame(name, image string) corev1alpha1.Frame {
+	return corev1alpha1.Frame{
+		Name: name,
+		Action: &corev1alpha1.Exec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Image: image}},
+				},
+			},
+		},
+	}
+}
+
+func TestDescribePlay(t *testing.T) {
+	h := enginetest.New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 2, Reason: "Error", Message: "Exited with code 2"})
+	play := h.RunPlay(&corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Spec: corev1alpha1.PlaySpec{Screenplays: []corev1alpha1.Screenplay{{
+			Name: "main",
+			Scenes: []corev1alpha1.Scene{
+				{Name: "build", Frames: []corev1alpha1.Frame{frame("compile", "fine"), frame("lint", "broken")}},
+				{Name: "deploy", Frames: []corev1alpha1.Frame{frame("release", "fine")}},
+			},
+		}}},
+	})
+	lint := play.Spec.Screenplays[0].Scenes[0].Frames[1].ID
+
+	started := metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	finished := metav1.NewTime(started.Add(time.Minute))
+	// Job which failed after it was retried once
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-1-lint",
+			Namespace: "default",
+			Labels:    map[string]string{kuberikRuntime.PlayLabel: "app-1", kuberikRuntime.FrameLabel: lint},
+		},
+		Status: batchv1.JobStatus{
+			StartTime:  &started,
+			Failed:     2,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: finished}},
+		},
+	}
+	kube := kubefake.NewSimpleClientset(job, &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "app-1.failed", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Play", Name: "app-1", UID: play.UID},
+		Type:           corev1.EventTypeWarning,
+		Reason:         kuberikRuntime.ReasonPlayFailed,
+		Message:        "Scene build failed",
+		LastTimestamp:  finished,
+	})
+
+	description, err := newPlayDescription(h.Clientset().CoreV1alpha1(), kube, "default", "app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := printDescription(out, description, "", finished.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Phase:      Failed",
+		"  main\n  ├── ✘ build\n",
+		"  │   ├── ✔ compile  exit 0\n",
+		"  │   └── ✘ lint  60s  exit 2  retried (2 attempts)  Error: Exited with code 2\n",
+		"  └── ⊘ deploy\n      └── ⊘ release  skipped\n",
+		"Warning  PlayFailed  60m  Scene build failed",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected description to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := printDescription(out, description, outputJSON, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	decoded := playDescription{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != "app-1" || decoded.Executions[lint].Job != "app-1-lint" || decoded.Executions[lint].Attempts != 2 || len(decoded.Events) != 1 {
+		t.Errorf("Expected description of the Play in JSON, got %s", out.String())
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/generated/clientset/versioned/typed/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var (
+	getPlaysMovie  *string
+	getPlaysPhase  *string
+	getPlaysOutput *string
+)
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+	getCmd.AddCommand(getPlaysCmd)
+	getPlaysMovie = getPlaysCmd.Flags().String("movie", "", "List Plays of the Movie only")
+	getPlaysPhase = getPlaysCmd.Flags().String("phase", "", "List Plays in the phase only, such as Running or Failed")
+	getPlaysOutput = addOutputFlag(getPlaysCmd)
+}
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Display Kuberik resources",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// getPlaysCmd represents the get plays command
+var getPlaysCmd = &cobra.Command{
+	Use:     "plays",
+	Aliases: []string{"play"},
+	Short:   "List Plays",
+	Long: `List Plays of the namespace from the oldest to the newest with their phase,
+age and the time they took to run.`,
+	Args: cobra.NoArgs,
+	Run:  getPlays,
+}
+
+func getPlays(cmd *cobra.Command, args []string) {
+	if err := validateOutput(*getPlaysOutput); err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	plays, err := listPlays(client, namespace, *getPlaysMovie, *getPlaysPhase)
+	if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	if err := printPlays(cmd.OutOrStdout(), plays, *getPlaysOutput, time.Now()); err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+}
+
+// listPlays lists Plays of the namespace, of the Movie and in the phase if set,
+// sorted by their creation
+func listPlays(kuberik v1alpha1.CoreV1alpha1Interface, namespace, movie, phase string) (*corev1alpha1.PlayList, error) {
+	options := metav1.ListOptions{}
+	if movie != "" {
+		options.LabelSelector = labels.SelectorFromSet(screener.MovieSelector(movie)).String()
+	}
+	plays, err := kuberik.Plays(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	if movie != "" || phase != "" {
+		var items []corev1alpha1.Play
+		for _, play := range plays.Items {
+			if (movie == "" || screener.MovieName(&play) == movie) && (phase == "" || string(play.Status.Phase) == phase) {
+				items = append(items, play)
+			}
+		}
+		plays.Items = items
+	}
+	sort.SliceStable(plays.Items, func(i, j int) bool {
+		return plays.Items[i].CreationTimestamp.Before(&plays.Items[j].CreationTimestamp)
+	})
+	return plays, nil
+}
+
+// printPlays prints the Plays as a table, or as a list in JSON or YAML
+func printPlays(out io.Writer, plays *corev1alpha1.PlayList, output string, now time.Time) error {
+	if output == outputJSON || output == outputYAML {
+		return printObject(out, output, plays)
+	}
+	if len(plays.Items) == 0 {
+		fmt.Fprintln(out, "No Plays found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	header := "NAME\tMOVIE\tPHASE\tAGE\tDURATION"
+	if output == outputWide {
+		header += "\tSCREENER\tSTARTED\tCOMPLETED\tRUNNER"
+	}
+	fmt.Fprintln(w, header)
+	for _, play := range plays.Items {
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s",
+			play.Name,
+			orNone(screener.MovieName(&play)),
+			orNone(string(play.Status.Phase)),
+			age(play.CreationTimestamp, now),
+			elapsed(play.Status.StartTime, play.Status.CompletionTime, now),
+		)
+		if output == outputWide {
+			row += fmt.Sprintf("\t%s\t%s\t%s\t%s",
+				orNone(screener.ScreenerName(&play)),
+				timestamp(play.Status.StartTime),
+				timestamp(play.Status.CompletionTime),
+				orNone(play.Status.Runner),
+			)
+		}
+		fmt.Fprintln(w, row)
+	}
+	return w.Flush()
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return none
+	}
+	return value
+}
+
+func timestamp(t *metav1.Time) string {
+	if t == nil || t.IsZero() {
+		return none
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	versionedfake "github.com/kuberik/kuberik/pkg/generated/clientset/versioned/fake"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPlays(t *testing.T) {
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPlay := func(name, movie string, phase corev1alpha1.PlayPhaseType, age time.Duration) *corev1alpha1.Play {
+		started := metav1.NewTime(created.Add(age + time.Second))
+		completed := metav1.NewTime(started.Add(90 * time.Second))
+		return &corev1alpha1.Play{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created.Add(age)),
+				Labels:            map[string]string{screener.MovieLabel: movie},
+			},
+			Status: corev1alpha1.PlayStatus{Phase: phase, StartTime: &started, CompletionTime: &completed},
+		}
+	}
+	kuberik := versionedfake.NewSimpleClientset()
+	for _, play := range []*corev1alpha1.Play{
+		newPlay("app-2", "app", corev1alpha1.PlayFailed, time.Minute),
+		newPlay("app-1", "app", corev1alpha1.PlayComplete, 0),
+		newPlay("web-1", "web", corev1alpha1.PlayComplete, 0),
+	} {
+		if err := kuberik.Tracker().Create(corev1alpha1.SchemeGroupVersion.WithResource("plays"), play, "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plays, err := listPlays(kuberik.CoreV1alpha1(), "default", "app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := printPlays(out, plays, "", created.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `NAME    MOVIE   PHASE      AGE   DURATION
+app-1   app     Complete   60m   90s
+app-2   app     Failed     59m   90s
+`
+	if out.String() != expected {
+		t.Errorf("Expected Plays of Movie app:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	plays, err = listPlays(kuberik.CoreV1alpha1(), "default", "", string(corev1alpha1.PlayFailed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := printPlays(out, plays, outputWide, created.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], "COMPLETED") || !strings.HasPrefix(lines[1], "app-2") {
+		t.Errorf("Expected wide table of failed Plays, got:\n%s", out.String())
+	}
+
+	if err := validateOutput("xml"); err == nil {
+		t.Error("Expected output format xml to be invalid")
+	}
+}
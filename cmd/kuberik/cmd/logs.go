@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/kubernetes"
+	"github.com/kuberik/kuberik/pkg/generated/clientset/versioned/typed/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/playtree"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// interval in which a followed Play is checked for new executions
+const logsPollInterval = 2 * time.Second
+
+// ANSI colors of frame prefixes
+var frameColors = []string{"\033[36m", "\033[33m", "\033[35m", "\033[32m", "\033[34m", "\033[31m"}
+
+var (
+	logsFrame  *string
+	logsFollow *bool
+	logsSince  *time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsFrame = logsCmd.Flags().String("frame", "", "Print logs of the frame with the name or ID only")
+	logsFollow = logsCmd.Flags().BoolP("follow", "f", false, "Follow logs until the Play finishes")
+	logsSince = logsCmd.Flags().Duration("since", 0, "Print logs newer than the duration, such as 5m. Archives created within it are printed whole")
+}
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs play/<name>",
+	Short: "Print logs of frames of a Play",
+	Long: `Print logs of containers of all executions of frames of a Play, each line
+prefixed with the frame. Logs of executions whose Pods are gone are printed
+from their archive. Archives are printed whole, and --since only skips
+archives created before the duration.
+
+The exit code is 0 if the Play completed or is still running, and 1 if it
+failed. With --follow, logs are followed until the Play finishes.`,
+	Args: cobra.ExactArgs(1),
+	Run:  printLogs,
+}
+
+func printLogs(cmd *cobra.Command, args []string) {
+	name, err := parsePlayArg(args[0])
+	if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	logs := newPlayLogs(client, kubeClient, namespace, cmd.OutOrStdout())
+	logs.frame, logs.follow, logs.since = *logsFrame, *logsFollow, *logsSince
+	logs.color = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	code, err := logs.print(name)
+	if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+// parsePlayArg returns the name of the Play referenced as play/<name>
+func parsePlayArg(arg string) (string, error) {
+	parts := strings.SplitN(arg, "/", 2)
+	if len(parts) != 2 || (parts[0] != "play" && parts[0] != "plays") || parts[1] == "" {
+		return "", fmt.Errorf("Expected play/<name>, got %s", arg)
+	}
+	return parts[1], nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// playLogs prints logs of executions of frames of a Play
+type playLogs struct {
+	kuberik   v1alpha1.CoreV1alpha1Interface
+	kube      kubeclient.Interface
+	namespace string
+	// frame limits logs to the frame with the name or ID
+	frame  string
+	follow bool
+	since  time.Duration
+	color  bool
+	// stream opens the output of the container of the Pod
+	stream func(pod *corev1.Pod, container string, options *corev1.PodLogOptions) (io.ReadCloser, error)
+
+	lock sync.Mutex
+	out  io.Writer
+	// prefixes of lines of frames by frame IDs
+	prefixes map[string]string
+	// printed containers, and executions printed from archives
+	printed map[string]bool
+	wg      sync.WaitGroup
+}
+
+func newPlayLogs(kuberik v1alpha1.CoreV1alpha1Interface, kube kubeclient.Interface, namespace string, out io.Writer) *playLogs {
+	l := &playLogs{kuberik: kuberik, kube: kube, namespace: namespace, out: out}
+	l.stream = func(pod *corev1.Pod, container string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+		options.Container = container
+		return l.kube.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Stream()
+	}
+	return l
+}
+
+// print prints logs of the Play and returns the exit code mirroring its result
+func (l *playLogs) print(name string) (int, error) {
+	play, err := l.kuberik.Plays(l.namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	if err := l.setPrefixes(play); err != nil {
+		return 0, err
+	}
+	l.printed = make(map[string]bool)
+	for {
+		if err := l.printExecutions(play); err != nil {
+			return 0, err
+		}
+		if !l.follow || playFinished(play) {
+			break
+		}
+		time.Sleep(logsPollInterval)
+		if play, err = l.kuberik.Plays(l.namespace).Get(name, metav1.GetOptions{}); err != nil {
+			return 0, err
+		}
+	}
+	l.wg.Wait()
+	if play.Status.Phase == corev1alpha1.PlayFailed || play.Status.Phase == corev1alpha1.PlayError {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// setPrefixes assigns colored prefixes to frames of the Play which are printed
+func (l *playLogs) setPrefixes(play *corev1alpha1.Play) error {
+	l.prefixes = make(map[string]string)
+	tree := playtree.New(play)
+	for _, screenplay := range tree.Screenplays {
+		for _, scene := range screenplay.Scenes {
+			for _, frame := range scene.Frames {
+				if l.frame != "" && frame.Name != l.frame && frame.ID != l.frame {
+					continue
+				}
+				prefix := fmt.Sprintf("[%s]", frame.Name)
+				if l.color {
+					prefix = frameColors[len(l.prefixes)%len(frameColors)] + prefix + "\033[0m"
+				}
+				l.prefixes[frame.ID] = prefix
+			}
+		}
+	}
+	if len(l.prefixes) == 0 && l.frame != "" {
+		return fmt.Errorf("Frame %s not found in Play %s", l.frame, play.Name)
+	}
+	return nil
+}
+
+// printExecutions prints logs of executions of the Play which aren't printed
+// yet in the order they were created. Executions are printed from their Pods,
+// or from their archive if the Pods are gone.
+func (l *playLogs) printExecutions(play *corev1alpha1.Play) error {
+	selector := labels.SelectorFromSet(kuberikRuntime.PlaySelector(play)).String()
+	archives, err := l.kube.CoreV1().ConfigMaps(l.namespace).List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,%s", selector, kubernetes.LogArchiveLabel),
+	})
+	if err != nil {
+		return err
+	}
+	archivesByJob := make(map[string]*corev1.ConfigMap)
+	for i := range archives.Items {
+		archivesByJob[archives.Items[i].Name] = &archives.Items[i]
+	}
+	jobs, err := l.kube.BatchV1().Jobs(l.namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+
+	// Executions are Jobs, and archives of Jobs which are gone
+	var executions []metav1.ObjectMeta
+	existing := make(map[string]bool)
+	for _, job := range jobs.Items {
+		executions = append(executions, job.ObjectMeta)
+		existing[job.Name] = true
+	}
+	for _, archive := range archives.Items {
+		if !existing[archive.Name] {
+			executions = append(executions, archive.ObjectMeta)
+		}
+	}
+	sort.SliceStable(executions, func(i, j int) bool {
+		return executions[i].CreationTimestamp.Before(&executions[j].CreationTimestamp)
+	})
+
+	for _, execution := range executions {
+		prefix, ok := l.prefixes[execution.Labels[kuberikRuntime.FrameLabel]]
+		if !ok || l.printed[execution.Name] {
+			continue
+		}
+		pods, err := l.kube.CoreV1().Pods(l.namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", execution.Name)})
+		if err != nil {
+			return err
+		}
+		for i := range pods.Items {
+			l.printPod(&pods.Items[i], prefix)
+		}
+		archive, archived := archivesByJob[execution.Name]
+		if len(pods.Items) > 0 || !archived {
+			continue
+		}
+		// Pods are gone, so the execution is printed from its archive once
+		l.printed[execution.Name] = true
+		if l.since == 0 || archive.CreationTimestamp.Time.After(time.Now().Add(-l.since)) {
+			l.printArchive(archive, prefix)
+		}
+	}
+	return nil
+}
+
+// printPod prints output of containers of the Pod which started. Output is
+// followed in the background when following the Play.
+func (l *playLogs) printPod(pod *corev1.Pod, prefix string) {
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	started := make(map[string]bool)
+	for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		started[status.Name] = status.State.Running != nil || status.State.Terminated != nil
+	}
+	for _, container := range containers {
+		key := kubernetes.LogArchiveKey(pod.Name, container.Name)
+		if l.printed[key] || !started[container.Name] {
+			continue
+		}
+		l.printed[key] = true
+		containerPrefix := prefix
+		if len(containers) > 1 {
+			containerPrefix = fmt.Sprintf("%s[%s]", prefix, container.Name)
+		}
+		options := &corev1.PodLogOptions{Follow: l.follow}
+		if l.since > 0 {
+			seconds := int64(l.since.Seconds())
+			options.SinceSeconds = &seconds
+		}
+		printContainer := func(pod *corev1.Pod, container string) {
+			stream, err := l.stream(pod, container, options)
+			if err != nil {
+				l.printLine(containerPrefix, fmt.Sprintf("Failed to get logs of pod %s: %s", pod.Name, err))
+				return
+			}
+			defer stream.Close()
+			l.printLines(containerPrefix, stream)
+		}
+		if l.follow {
+			l.wg.Add(1)
+			go func(container string) {
+				defer l.wg.Done()
+				printContainer(pod, container)
+			}(container.Name)
+		} else {
+			printContainer(pod, container.Name)
+		}
+	}
+}
+
+// printArchive prints archived output of containers of an execution
+func (l *playLogs) printArchive(archive *corev1.ConfigMap, prefix string) {
+	keys := make([]string, 0, len(archive.Data))
+	for key := range archive.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		containerPrefix := prefix
+		if len(keys) > 1 {
+			_, container := kubernetes.ParseLogArchiveKey(key)
+			containerPrefix = fmt.Sprintf("%s[%s]", prefix, container)
+		}
+		l.printLines(containerPrefix, strings.NewReader(archive.Data[key]))
+	}
+}
+
+func (l *playLogs) printLines(prefix string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		l.printLine(prefix, scanner.Text())
+	}
+}
+
+func (l *playLogs) printLine(prefix, line string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	fmt.Fprintf(l.out, "%s %s\n", prefix, line)
+}
+
+func playFinished(play *corev1alpha1.Play) bool {
+	switch play.Status.Phase {
+	case corev1alpha1.PlayComplete, corev1alpha1.PlayFailed, corev1alpha1.PlayError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/kubernetes"
+	versionedfake "github.com/kuberik/kuberik/pkg/generated/clientset/versioned/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestPlayLogs creates a printer of logs of a failed Play whose frame
+// compile has a Pod and frame lint has an archive of a Job which is gone
+func newTestPlayLogs(t *testing.T, out io.Writer) *playLogs {
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Spec: corev1alpha1.PlaySpec{Screenplays: []corev1alpha1.Screenplay{{
+			Name: "main",
+			Scenes: []corev1alpha1.Scene{
+				{Name: "build", Frames: []corev1alpha1.Frame{{ID: "a", Name: "compile"}, {ID: "b", Name: "lint"}}},
+			},
+		}}},
+		Status: corev1alpha1.PlayStatus{Phase: corev1alpha1.PlayFailed},
+	}
+	kuberik := versionedfake.NewSimpleClientset()
+	if err := kuberik.Tracker().Create(corev1alpha1.SchemeGroupVersion.WithResource("plays"), play, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	meta := func(name, frame string, age time.Duration) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created.Add(age)),
+			Labels: map[string]string{
+				kuberikRuntime.PlayLabel:   "app-1",
+				kuberikRuntime.FrameLabel:  frame,
+				kubernetes.LogArchiveLabel: "true",
+			},
+		}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: meta("app-1-compile-abcde", "a", 0),
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "main",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+		}}},
+	}
+	pod.Labels["job-name"] = "app-1-compile"
+	kube := kubefake.NewSimpleClientset(
+		&batchv1.Job{ObjectMeta: meta("app-1-compile", "a", 0)},
+		pod,
+		&corev1.ConfigMap{ObjectMeta: meta("app-1-lint", "b", time.Second), Data: map[string]string{
+			kubernetes.LogArchiveKey("app-1-lint-fghij", "main"): "lint failed\n",
+		}},
+	)
+
+	logs := newPlayLogs(kuberik.CoreV1alpha1(), kube, "default", out)
+	// Fake clientset doesn't stream logs
+	logs.stream = func(pod *corev1.Pod, container string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("compiled\n")), nil
+	}
+	return logs
+}
+
+func TestPlayLogs(t *testing.T) {
+	out := &bytes.Buffer{}
+	code, err := newTestPlayLogs(t, out).print("app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[compile] compiled\n[lint] lint failed\n"; out.String() != expected {
+		t.Errorf("Expected logs %q, got %q", expected, out.String())
+	}
+	if code != 1 {
+		t.Errorf("Expected exit code of failed Play to be 1, got %d", code)
+	}
+
+	out.Reset()
+	logs := newTestPlayLogs(t, out)
+	logs.frame = "lint"
+	if _, err := logs.print("app-1"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[lint] lint failed\n"; out.String() != expected {
+		t.Errorf("Expected logs of frame lint %q, got %q", expected, out.String())
+	}
+
+	logs = newTestPlayLogs(t, out)
+	logs.frame = "missing"
+	if _, err := logs.print("app-1"); err == nil {
+		t.Error("Expected missing frame to fail")
+	}
+}
+
+func TestParsePlayArg(t *testing.T) {
+	if name, err := parsePlayArg("play/app-1"); err != nil || name != "app-1" {
+		t.Errorf("Expected Play app-1, got %q: %v", name, err)
+	}
+	for _, arg := range []string{"app-1", "movie/app", "play/"} {
+		if _, err := parsePlayArg(arg); err == nil {
+			t.Errorf("Expected %q to be invalid", arg)
+		}
+	}
+}
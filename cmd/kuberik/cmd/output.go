@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats of commands printing objects
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+	outputWide = "wide"
+)
+
+// value printed in place of missing values of columns
+const none = "-"
+
+// addOutputFlag adds the flag selecting the output format to the command
+func addOutputFlag(cmd *cobra.Command) *string {
+	return cmd.Flags().StringP("output", "o", "", "Output format: json, yaml or wide")
+}
+
+func validateOutput(output string) error {
+	switch output {
+	case "", outputJSON, outputYAML, outputWide:
+		return nil
+	}
+	return fmt.Errorf("Unknown output format %s, expected json, yaml or wide", output)
+}
+
+// printObject prints the object as JSON or YAML
+func printObject(out io.Writer, output string, obj interface{}) error {
+	if output == outputYAML {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(obj)
+}
+
+// age returns the time since the timestamp in the format of kubectl
+func age(t metav1.Time, now time.Time) string {
+	if t.IsZero() {
+		return none
+	}
+	return duration.HumanDuration(now.Sub(t.Time))
+}
+
+// elapsed returns the time between start and end, or until now if it hasn't ended
+func elapsed(start, end *metav1.Time, now time.Time) string {
+	if start == nil || start.IsZero() {
+		return none
+	}
+	if end != nil && !end.IsZero() {
+		now = end.Time
+	}
+	return duration.HumanDuration(now.Sub(start.Time))
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -103,6 +104,10 @@ func playFromMovie(movie *corev1alpha1.Movie) corev1alpha1.Play {
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: fmt.Sprintf("%s-", movie.Name),
 			Namespace:    movie.Namespace,
+			Labels:       screener.MovieSelector(movie.Name),
+			Annotations: map[string]string{
+				screener.MovieAnnotation: movie.Name,
+			},
 		},
 		Spec: movie.Spec.Template.Spec,
 		Status: corev1alpha1.PlayStatus{
@@ -4,11 +4,13 @@ import (
 	conf "github.com/kuberik/kuberik/pkg/engine/config"
 	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func initEngine(config *rest.Config, client client.Client, namespace string) {
+func initEngine(config *rest.Config, client client.Client, recorder record.EventRecorder, namespace string) error {
 	conf.InitConfig(config)
 	conf.InitClient(client)
-	scheduler.InitEngine()
+	conf.InitRecorder(recorder)
+	return scheduler.InitEngine()
 }
@@ -7,17 +7,20 @@ import (
 	"os"
 	"runtime"
 
+	// PostgreSQL can be used as the history database shared by replicas
+	_ "github.com/jinzhu/gorm/dialects/postgres"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 
+	"github.com/kuberik/kuberik/pkg/api"
 	"github.com/kuberik/kuberik/pkg/apis"
 	"github.com/kuberik/kuberik/pkg/controller"
 	kuberikConfig "github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/tracing"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
-	"github.com/operator-framework/operator-sdk/pkg/leader"
 	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	"github.com/operator-framework/operator-sdk/pkg/restmapper"
@@ -25,6 +28,7 @@ import (
 	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -53,6 +57,15 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	pflag.StringVar(&kuberikConfig.Scheduler, "scheduler", kuberikConfig.Scheduler, "Default scheduler executing Plays: kubernetes, container or shell (env KUBERIK_SCHEDULER)")
+	pflag.StringVar(&kuberikConfig.OTLPEndpoint, "otlp-endpoint", kuberikConfig.OTLPEndpoint, "OTLP/HTTP endpoint traces of Plays are exported to, tracing is disabled if empty (env KUBERIK_OTLP_ENDPOINT)")
+	pflag.StringVar(&kuberikConfig.APIAddress, "api-address", kuberikConfig.APIAddress, "Address the REST API is served on, the API isn't served if empty (env KUBERIK_API_ADDRESS)")
+	pflag.StringSliceVar(&kuberikConfig.APIAllowedOrigins, "api-allowed-origins", kuberikConfig.APIAllowedOrigins, "Origins from which browsers can call the REST API (env KUBERIK_API_ALLOWED_ORIGINS)")
+	pflag.StringVar(&kuberikConfig.HistoryDialect, "history-dialect", kuberikConfig.HistoryDialect, "Database dialect of the history of Plays (env KUBERIK_HISTORY_DIALECT)")
+	pflag.StringVar(&kuberikConfig.HistoryDSN, "history-dsn", kuberikConfig.HistoryDSN, "Data source of the history of Plays, such as a path of the SQLite database, history isn't recorded if empty (env KUBERIK_HISTORY_DSN)")
+	pflag.IntVar(&kuberikConfig.LogArchiveLines, "log-archive-lines", kuberikConfig.LogArchiveLines, "Last lines of output of each container of finished executions kept in ConfigMaps, logs aren't archived if 0 (env KUBERIK_LOG_ARCHIVE_LINES)")
+	pflag.Parse()
+
 	// Use a zap logr.Logger implementation. If none of the zap
 	// flags are configured (or if the zap flag set is not being
 	// used), this defaults to a production zap logger.
@@ -79,18 +92,19 @@ func main() {
 	}
 
 	ctx := context.TODO()
-	// Become the leader before proceeding
-	err = leader.Become(ctx, "kuberik-lock")
-	if err != nil {
-		log.Error(err, "")
-		os.Exit(1)
-	}
+	// All replicas play Plays, while other controllers run only on the elected
+	// leader. Leader election is skipped when running outside of the cluster.
+	operatorNamespace, err := k8sutil.GetOperatorNamespace()
+	leaderElection := err == nil
 
 	// Create a new Cmd to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, manager.Options{
-		Namespace:          namespace,
-		MapperProvider:     restmapper.NewDynamicRESTMapper,
-		MetricsBindAddress: fmt.Sprintf("%s:%d", kuberikConfig.Host, metricsPort),
+		Namespace:               namespace,
+		MapperProvider:          restmapper.NewDynamicRESTMapper,
+		MetricsBindAddress:      fmt.Sprintf("%s:%d", kuberikConfig.Host, metricsPort),
+		LeaderElection:          leaderElection,
+		LeaderElectionID:        "kuberik-leader",
+		LeaderElectionNamespace: operatorNamespace,
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -105,12 +119,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	// History is recorded by the Play controller, so it's opened before the controllers are set up
+	if err := kuberikConfig.InitHistory(); err != nil {
+		log.Error(err, "Failed to open the history database")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	if kuberikConfig.APIAddress != "" {
+		if err := addAPIServer(mgr, cfg); err != nil {
+			log.Error(err, "Failed to set up the API server")
+			os.Exit(1)
+		}
+	}
+
 	if err = serveCRMetrics(cfg); err != nil {
 		log.Info("Could not generate and serve custom resource metrics", "error", err.Error())
 	}
@@ -141,7 +168,18 @@ func main() {
 
 	log.Info("Starting the Cmd.")
 
-	initEngine(cfg, mgr.GetClient(), namespace)
+	if err := initEngine(cfg, mgr.GetClient(), mgr.GetEventRecorderFor("kuberik"), namespace); err != nil {
+		log.Error(err, "Failed to initialize the engine")
+		os.Exit(1)
+	}
+	if kuberikConfig.OTLPEndpoint != "" {
+		shutdown, err := tracing.Init(ctx, kuberikConfig.OTLPEndpoint)
+		if err != nil {
+			log.Error(err, "Failed to initialize tracing")
+			os.Exit(1)
+		}
+		defer shutdown(ctx)
+	}
 	// Start the Cmd
 	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
 		log.Error(err, "Manager exited non-zero")
@@ -149,6 +187,20 @@ func main() {
 	}
 }
 
+// addAPIServer adds the REST API server to the manager, so it's served by all replicas
+func addAPIServer(mgr manager.Manager, cfg *rest.Config) error {
+	kube, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	server := api.NewServer(kuberikConfig.APIAddress, mgr.GetClient(), kube)
+	server.AllowedOrigins = kuberikConfig.APIAllowedOrigins
+	if err := server.Watch(mgr.GetCache()); err != nil {
+		return err
+	}
+	return mgr.Add(server)
+}
+
 // serveCRMetrics gets the Operator/CustomResource GVKs and generates metrics based on those types.
 // It serves those metrics on "http://metricsHost:operatorMetricsPort".
 func serveCRMetrics(cfg *rest.Config) error {
@@ -68,6 +68,7 @@ function getUsageSidebar() {
       ['', 'Introduction'],
       'terminology',
       'screeners',
+      'dashboard',
     ]
   }, {
     title: "Advanced",
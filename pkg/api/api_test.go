@@ -0,0 +1,367 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kuberik/kuberik/pkg/apis"
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/playtree"
+	"github.com/kuberik/kuberik/pkg/screener"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	// token of a user who can do anything
+	adminToken = "admin"
+	// token of a user who can only get and list
+	viewerToken = "viewer"
+	// token of a user who can only create Plays and read logs of Pods
+	runnerToken = "runner"
+)
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// newServer creates a test server of the API with the objects. Users are
+// authenticated and authorized by fake reviews of the tokens.
+func newServer(t *testing.T, objs ...runtime.Object) (*httptest.Server, client.Client) {
+	apiServer, c := newAPIServer(t, objs...)
+	return httptest.NewServer(apiServer.Handler()), c
+}
+
+func newAPIServer(t *testing.T, objs ...runtime.Object) (*Server, client.Client) {
+	s := testScheme(t)
+	var kubeObjs, kuberikObjs []runtime.Object
+	for _, obj := range objs {
+		if _, ok := obj.(*corev1.Pod); ok {
+			kubeObjs = append(kubeObjs, obj)
+		} else {
+			kuberikObjs = append(kuberikObjs, obj)
+		}
+	}
+	c := fakeclient.NewFakeClientWithScheme(s, kuberikObjs...)
+	kube := kubefake.NewSimpleClientset(kubeObjs...)
+	kube.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == adminToken || review.Spec.Token == viewerToken || review.Spec.Token == runnerToken {
+			review.Status.Authenticated = true
+			review.Status.User.Username = review.Spec.Token
+		}
+		return true, review, nil
+	})
+	kube.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		switch review.Spec.User {
+		case adminToken:
+			review.Status.Allowed = true
+		case viewerToken:
+			review.Status.Allowed = attributes.Verb == "get" || attributes.Verb == "list"
+		case runnerToken:
+			review.Status.Allowed = (attributes.Verb == "create" && attributes.Resource == "plays") || attributes.Subresource == "log"
+		}
+		return true, review, nil
+	})
+	apiServer := NewServer("", c, kube)
+	// Fake clientset doesn't stream logs
+	apiServer.logs = func(ctx context.Context, pod *corev1.Pod, container string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("fake logs\n")), nil
+	}
+	return apiServer, c
+}
+
+func request(t *testing.T, server *httptest.Server, method, path, token string, body interface{}) (int, []byte) {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req, err := http.NewRequest(method, server.URL+Prefix+path, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, respBody
+}
+
+func movie() *corev1alpha1.Movie {
+	return &corev1alpha1.Movie{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: corev1alpha1.MovieSpec{Template: corev1alpha1.PlayTemplate{Spec: corev1alpha1.PlaySpec{
+			Vars: corev1alpha1.Vars{{Name: "VERSION", Value: "1"}},
+			Screenplays: []corev1alpha1.Screenplay{{
+				Name: "main",
+				Scenes: []corev1alpha1.Scene{
+					{Name: "build", Frames: []corev1alpha1.Frame{{ID: "a", Name: "compile"}, {ID: "b", Name: "lint"}}},
+					{Name: "deploy", Frames: []corev1alpha1.Frame{{ID: "c", Name: "release"}}},
+				},
+			}},
+		}}},
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	server, _ := newServer(t)
+	defer server.Close()
+
+	for _, token := range []string{"", "invalid"} {
+		if status, body := request(t, server, http.MethodGet, "/namespaces/default/movies", token, nil); status != http.StatusUnauthorized {
+			t.Errorf("Expected request with token %q to be unauthorized, got %d: %s", token, status, body)
+		}
+	}
+	if status, body := request(t, server, http.MethodGet, "/namespaces/default/movies?access_token="+viewerToken, "", nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected access token parameter to be accepted only by streams, got %d: %s", status, body)
+	}
+}
+
+func TestTriggerPlay(t *testing.T) {
+	server, c := newServer(t, movie())
+	defer server.Close()
+
+	trigger := TriggerRequest{Vars: map[string]string{"VERSION": "2"}}
+	if status, _ := request(t, server, http.MethodPost, "/namespaces/default/movies/app/plays", viewerToken, trigger); status != http.StatusForbidden {
+		t.Errorf("Expected viewer to be forbidden to trigger Plays, got %d", status)
+	}
+	if status, _ := request(t, server, http.MethodPost, "/namespaces/default/movies/app/plays", runnerToken, trigger); status != http.StatusForbidden {
+		t.Errorf("Expected user who can't get the Movie to be forbidden to trigger its Plays, got %d", status)
+	}
+	status, body := request(t, server, http.MethodPost, "/namespaces/default/movies/app/plays", adminToken, trigger)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected Play to be created, got %d: %s", status, body)
+	}
+	tree := playtree.Tree{}
+	if err := json.Unmarshal(body, &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	play := &corev1alpha1.Play{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: tree.Name}, play); err != nil {
+		t.Fatal(err)
+	}
+	if play.Labels[screener.MovieLabel] != "app" || play.Spec.Vars[0].Value != "2" {
+		t.Errorf("Expected Play of the Movie with the vars, got %+v", play)
+	}
+}
+
+func TestGetPlay(t *testing.T) {
+	play := screener.NewPlay(movie(), "", nil)
+	play.Name = "app-1"
+	play.Status = corev1alpha1.PlayStatus{
+		Phase:         corev1alpha1.PlayRunning,
+		Frames:        map[string]int{"a": 0, "b": 2},
+		FrameFailures: map[string]corev1alpha1.FrameFailure{"b": {Reason: "Error", Message: "Exited with code 2"}},
+	}
+	server, _ := newServer(t, play)
+	defer server.Close()
+
+	status, body := request(t, server, http.MethodGet, "/namespaces/default/plays/app-1", viewerToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected Play, got %d: %s", status, body)
+	}
+	tree := playtree.Tree{}
+	if err := json.Unmarshal(body, &tree); err != nil {
+		t.Fatal(err)
+	}
+	scenes := tree.Screenplays[0].Scenes
+	statuses := []playtree.Status{scenes[0].Status, scenes[0].Frames[0].Status, scenes[0].Frames[1].Status, scenes[1].Status}
+	expected := []playtree.Status{playtree.StatusFailed, playtree.StatusSucceeded, playtree.StatusFailed, playtree.StatusPending}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("Expected statuses %v, got %v", expected, statuses)
+			break
+		}
+	}
+	if failure := scenes[0].Frames[1].Failure; failure == nil || failure.Reason != "Error" {
+		t.Errorf("Expected failure of frame lint, got %v", failure)
+	}
+
+	if status, _ := request(t, server, http.MethodGet, "/namespaces/default/plays/missing", viewerToken, nil); status != http.StatusNotFound {
+		t.Errorf("Expected missing Play not to be found, got %d", status)
+	}
+}
+
+func TestCancelPlay(t *testing.T) {
+	play := screener.NewPlay(movie(), "", nil)
+	play.Name = "app-1"
+	play.Status.Phase = corev1alpha1.PlayRunning
+	server, c := newServer(t, play)
+	defer server.Close()
+
+	if status, body := request(t, server, http.MethodPost, "/namespaces/default/plays/app-1/cancel", adminToken, nil); status != http.StatusAccepted {
+		t.Fatalf("Expected Play to be cancelled, got %d: %s", status, body)
+	}
+	cancelled := &corev1alpha1.Play{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "app-1"}, cancelled); err != nil {
+		t.Fatal(err)
+	}
+	if !cancelled.Spec.Cancel {
+		t.Error("Expected Play to be marked as cancelled")
+	}
+}
+
+func TestFrameLogs(t *testing.T) {
+	play := screener.NewPlay(movie(), "", nil)
+	play.Name = "app-1"
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-1-build-compile-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{kuberikRuntime.PlayLabel: "app-1", kuberikRuntime.FrameLabel: "a"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}},
+	}
+	server, _ := newServer(t, play, pod)
+	defer server.Close()
+
+	if status, _ := request(t, server, http.MethodGet, "/namespaces/default/plays/app-1/frames/compile/logs", runnerToken, nil); status != http.StatusForbidden {
+		t.Errorf("Expected user who can't get the Play to be forbidden to read its logs, got %d", status)
+	}
+	status, body := request(t, server, http.MethodGet, "/namespaces/default/plays/app-1/frames/compile/logs", viewerToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected logs, got %d: %s", status, body)
+	}
+	expected := "event: log\ndata: {\"frame\":\"a\",\"pod\":\"app-1-build-compile-abcde\",\"container\":\"main\",\"line\":\"fake logs\"}\n\nevent: end\ndata: {}\n\n"
+	if string(body) != expected {
+		t.Errorf("Expected events %q, got %q", expected, body)
+	}
+
+	if status, _ := request(t, server, http.MethodGet, "/namespaces/default/plays/app-1/frames/release/logs", viewerToken, nil); status != http.StatusNotFound {
+		t.Errorf("Expected frame without executions not to have logs, got %d", status)
+	}
+}
+
+// readEvent reads the next server-sent event of the stream
+func readEvent(t *testing.T, stream *bufio.Reader) (string, Event) {
+	var name string
+	event := Event{}
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %s", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, event
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestPlayEvents(t *testing.T) {
+	play := screener.NewPlay(movie(), "", nil)
+	play.Name = "app-1"
+	play.UID = "uid"
+	play.Status.Phase = corev1alpha1.PlayRunning
+	apiServer, _ := newAPIServer(t, play)
+	informers := &informertest.FakeInformers{Scheme: testScheme(t)}
+	if err := apiServer.Watch(informers); err != nil {
+		t.Fatal(err)
+	}
+	playInformer, _ := informers.FakeInformerFor(&corev1alpha1.Play{})
+	jobInformer, _ := informers.FakeInformerFor(&batchv1.Job{})
+	server := httptest.NewServer(apiServer.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + Prefix + "/namespaces/default/plays/app-1/events?access_token=" + viewerToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected event stream, got %d", resp.StatusCode)
+	}
+	stream := bufio.NewReader(resp.Body)
+	if name, event := readEvent(t, stream); name != "snapshot" || event.Version != EventsVersion || event.Tree == nil || event.Tree.Phase != corev1alpha1.PlayRunning {
+		t.Fatalf("Expected snapshot of the running Play, got %s %+v", name, event)
+	}
+
+	finished := play.DeepCopy()
+	finished.Status.Frames = map[string]int{"a": 0}
+	playInformer.Update(play, finished)
+	if name, event := readEvent(t, stream); name != "frame" || event.Scene != "build" || event.Frame.ID != "a" || event.Frame.Status != playtree.StatusSucceeded {
+		t.Errorf("Expected frame compile to succeed, got %s %+v", name, event)
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app-1-lint-abcde",
+			Namespace:       "default",
+			Labels:          map[string]string{kuberikRuntime.FrameLabel: "b", kuberikRuntime.AttemptLabel: "1"},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(play, corev1alpha1.SchemeGroupVersion.WithKind("Play"))},
+		},
+		Status: batchv1.JobStatus{Active: 1},
+	}
+	jobInformer.Add(job)
+	if name, event := readEvent(t, stream); name != "execution" || event.Execution.Frame != "b" || event.Execution.Attempt != 1 || event.Execution.Active != 1 {
+		t.Errorf("Expected execution of frame lint, got %s %+v", name, event)
+	}
+
+	playInformer.Delete(finished)
+	if name, _ := readEvent(t, stream); name != "deleted" {
+		t.Errorf("Expected Play to be deleted, got %s", name)
+	}
+	if _, err := stream.ReadString('\n'); err != io.EOF {
+		t.Errorf("Expected stream to end after the Play was deleted, got %v", err)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	server, _ := newServer(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `<script src="app.js">`) {
+		t.Errorf("Expected index of the dashboard, got %d: %s", resp.StatusCode, body)
+	}
+}
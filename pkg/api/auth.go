@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// accessTokenParam is the query parameter holding the token of callers which
+// can't set headers, such as EventSource of browsers. It's only accepted by
+// streams, since URLs of other requests end up in logs and browser history.
+const accessTokenParam = "access_token"
+
+type userKey struct{}
+
+// authenticate returns a middleware which reviews the bearer token of the
+// request and stores the authenticated user in the context of the request.
+// The token is also read from the access token parameter if it's accepted.
+func (s *Server) authenticate(acceptAccessToken bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == r.Header.Get("Authorization") {
+				token = ""
+			}
+			if token == "" && acceptAccessToken {
+				token = r.URL.Query().Get(accessTokenParam)
+			}
+			if token == "" {
+				writeError(w, http.StatusUnauthorized, "Bearer token is required")
+				return
+			}
+			review, err := s.kube.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+				Spec: authenticationv1.TokenReviewSpec{Token: token},
+			})
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "Failed to review token: %s", err)
+				return
+			}
+			if !review.Status.Authenticated {
+				writeError(w, http.StatusUnauthorized, "Token isn't valid: %s", review.Status.Error)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, review.Status.User)))
+		})
+	}
+}
+
+// authorize reviews whether the user of the request can access the resource.
+// It responds to the request and returns false if the access isn't allowed.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, resource authorizationv1.ResourceAttributes) bool {
+	user, _ := r.Context().Value(userKey{}).(authenticationv1.UserInfo)
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review, err := s.kube.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &resource,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to review access: %s", err)
+		return false
+	}
+	if !review.Status.Allowed {
+		writeError(w, http.StatusForbidden, "User %s can't %s %s in namespace %s", user.Username, resource.Verb, resourceName(resource), resource.Namespace)
+		return false
+	}
+	return true
+}
+
+// kuberikResource returns attributes of the Kuberik resource in the namespace
+func kuberikResource(verb, resource, namespace, name string) authorizationv1.ResourceAttributes {
+	return authorizationv1.ResourceAttributes{
+		Verb:      verb,
+		Group:     corev1alpha1.SchemeGroupVersion.Group,
+		Resource:  resource,
+		Namespace: namespace,
+		Name:      name,
+	}
+}
+
+func resourceName(resource authorizationv1.ResourceAttributes) string {
+	name := resource.Resource
+	if resource.Subresource != "" {
+		name += "/" + resource.Subresource
+	}
+	if resource.Name != "" {
+		name += " " + resource.Name
+	}
+	return name
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/playtree"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// EventsVersion is the version of the model of events sent by Play event
+// streams. Fields are only added within a version, changes which break
+// clients bump it.
+const EventsVersion = "v1"
+
+// EventType is the type of an event of a Play event stream
+type EventType string
+
+// Types of events of Play event streams
+const (
+	// EventSnapshot carries the tree of the Play. It's the first event of every stream.
+	EventSnapshot EventType = "snapshot"
+	// EventPhase is sent when the phase of the Play changes
+	EventPhase EventType = "phase"
+	// EventScene is sent when the status of a scene changes
+	EventScene EventType = "scene"
+	// EventFrame is sent when the status of a frame changes
+	EventFrame EventType = "frame"
+	// EventExecution is sent when a Job executing a frame changes
+	EventExecution EventType = "execution"
+	// EventLog carries a line of output of a container executing a frame
+	EventLog EventType = "log"
+	// EventDeleted is sent when the Play is deleted. It's the last event of the stream.
+	EventDeleted EventType = "deleted"
+)
+
+const (
+	// events buffered for each stream before the stream is closed as too slow
+	eventsBuffer = 256
+	// interval of comments keeping idle streams open through proxies
+	keepaliveInterval = 30 * time.Second
+	// interval in which Pods waiting to start are checked again before following their logs
+	pendingPodsInterval = 2 * time.Second
+)
+
+// Event is an update of a Play sent by the Play event stream
+type Event struct {
+	Version   string    `json:"version"`
+	Type      EventType `json:"type"`
+	Namespace string    `json:"namespace"`
+	Play      string    `json:"play"`
+	// Tree is set for snapshot events
+	Tree *playtree.Tree `json:"tree,omitempty"`
+	// Phase is set for phase events
+	Phase corev1alpha1.PlayPhaseType `json:"phase,omitempty"`
+	// Screenplay and Scene locate scene and frame events
+	Screenplay string `json:"screenplay,omitempty"`
+	Scene      string `json:"scene,omitempty"`
+	// Status is set for scene events
+	Status playtree.Status `json:"status,omitempty"`
+	// Frame is set for frame events
+	Frame *playtree.Frame `json:"frame,omitempty"`
+	// Execution is set for execution events
+	Execution *Execution `json:"execution,omitempty"`
+	// Log is set for log events
+	Log *LogLine `json:"log,omitempty"`
+}
+
+// Execution is the state of a Job executing a frame
+type Execution struct {
+	Frame          string       `json:"frame"`
+	Job            string       `json:"job"`
+	Attempt        int          `json:"attempt"`
+	Active         int32        `json:"active"`
+	Succeeded      int32        `json:"succeeded"`
+	Failed         int32        `json:"failed"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// Watch registers handlers on informers of Plays and Jobs which push updates
+// to Play event streams. Event streams aren't served until it's called.
+func (s *Server) Watch(informers cache.Informers) error {
+	h := newHub()
+	playInformer, err := informers.GetInformer(&corev1alpha1.Play{})
+	if err != nil {
+		return err
+	}
+	playInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    h.playChanged,
+		UpdateFunc: func(_, obj interface{}) { h.playChanged(obj) },
+		DeleteFunc: h.playDeleted,
+	})
+	jobInformer, err := informers.GetInformer(&batchv1.Job{})
+	if err != nil {
+		return err
+	}
+	jobInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    h.jobChanged,
+		UpdateFunc: func(_, obj interface{}) { h.jobChanged(obj) },
+	})
+	s.hub = h
+	return nil
+}
+
+// subscription is a stream of events of a Play
+type subscription struct {
+	key    types.NamespacedName
+	events chan Event
+}
+
+// hub broadcasts updates of Plays observed by informers to subscribed event
+// streams. Trees are only kept for Plays with subscriptions, so changes are
+// found by comparing the tree of the updated Play with the last one.
+type hub struct {
+	lock          sync.Mutex
+	subscriptions map[types.NamespacedName]map[*subscription]struct{}
+	trees         map[types.NamespacedName]playtree.Tree
+}
+
+func newHub() *hub {
+	return &hub{
+		subscriptions: make(map[types.NamespacedName]map[*subscription]struct{}),
+		trees:         make(map[types.NamespacedName]playtree.Tree),
+	}
+}
+
+// subscribe subscribes to events of the Play and returns the snapshot the
+// events follow
+func (h *hub) subscribe(play *corev1alpha1.Play) (*subscription, playtree.Tree) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	key := types.NamespacedName{Namespace: play.Namespace, Name: play.Name}
+	tree, ok := h.trees[key]
+	if !ok {
+		tree = playtree.New(play)
+		h.trees[key] = tree
+	}
+	sub := &subscription{key: key, events: make(chan Event, eventsBuffer)}
+	if h.subscriptions[key] == nil {
+		h.subscriptions[key] = make(map[*subscription]struct{})
+	}
+	h.subscriptions[key][sub] = struct{}{}
+	return sub, tree
+}
+
+func (h *hub) unsubscribe(sub *subscription) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.subscriptions[sub.key][sub]; ok {
+		h.remove(sub)
+	}
+}
+
+// remove closes the subscription and forgets the tree of the Play once it has
+// no subscriptions. It's called with the lock held.
+func (h *hub) remove(sub *subscription) {
+	close(sub.events)
+	delete(h.subscriptions[sub.key], sub)
+	if len(h.subscriptions[sub.key]) == 0 {
+		delete(h.subscriptions, sub.key)
+		delete(h.trees, sub.key)
+	}
+}
+
+// publish sends the events to subscriptions of the Play. Subscriptions which
+// can't keep up are closed, so their clients reconnect and get a new snapshot.
+// It's called with the lock held.
+func (h *hub) publish(key types.NamespacedName, events ...Event) {
+	for sub := range h.subscriptions[key] {
+		for _, event := range events {
+			event.Version, event.Namespace, event.Play = EventsVersion, key.Namespace, key.Name
+			select {
+			case sub.events <- event:
+				continue
+			default:
+			}
+			h.remove(sub)
+			break
+		}
+	}
+}
+
+func (h *hub) playChanged(obj interface{}) {
+	play, ok := obj.(*corev1alpha1.Play)
+	if !ok {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	key := types.NamespacedName{Namespace: play.Namespace, Name: play.Name}
+	if len(h.subscriptions[key]) == 0 {
+		return
+	}
+	tree := playtree.New(play)
+	h.publish(key, treeChanges(h.trees[key], tree)...)
+	if _, ok := h.trees[key]; ok {
+		h.trees[key] = tree
+	}
+}
+
+func (h *hub) playDeleted(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	play, ok := obj.(*corev1alpha1.Play)
+	if !ok {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	key := types.NamespacedName{Namespace: play.Namespace, Name: play.Name}
+	h.publish(key, Event{Type: EventDeleted})
+	for sub := range h.subscriptions[key] {
+		h.remove(sub)
+	}
+}
+
+func (h *hub) jobChanged(obj interface{}) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return
+	}
+	owner := metav1.GetControllerOf(job)
+	if owner == nil || owner.Kind != "Play" {
+		return
+	}
+	attempt, _ := strconv.Atoi(job.Labels[kuberikRuntime.AttemptLabel])
+	execution := &Execution{
+		Frame:          job.Labels[kuberikRuntime.FrameLabel],
+		Job:            job.Name,
+		Attempt:        attempt,
+		Active:         job.Status.Active,
+		Succeeded:      job.Status.Succeeded,
+		Failed:         job.Status.Failed,
+		StartTime:      job.Status.StartTime,
+		CompletionTime: job.Status.CompletionTime,
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.publish(types.NamespacedName{Namespace: job.Namespace, Name: owner.Name}, Event{Type: EventExecution, Execution: execution})
+}
+
+// treeChanges returns events of changes between the trees of a Play
+func treeChanges(old, new playtree.Tree) []Event {
+	var events []Event
+	if old.Phase != new.Phase {
+		events = append(events, Event{Type: EventPhase, Phase: new.Phase})
+	}
+	scenes := make(map[string]playtree.Scene)
+	frames := make(map[string]playtree.Frame)
+	for _, screenplay := range old.Screenplays {
+		for _, scene := range screenplay.Scenes {
+			scenes[screenplay.Name+"/"+scene.Name] = scene
+			for _, frame := range scene.Frames {
+				frames[frame.ID] = frame
+			}
+		}
+	}
+	for _, screenplay := range new.Screenplays {
+		for _, scene := range screenplay.Scenes {
+			if scenes[screenplay.Name+"/"+scene.Name].Status != scene.Status {
+				events = append(events, Event{Type: EventScene, Screenplay: screenplay.Name, Scene: scene.Name, Status: scene.Status})
+			}
+			for i, frame := range scene.Frames {
+				oldFrame := frames[frame.ID]
+				if oldFrame.Status != frame.Status || !sameExitCode(oldFrame.ExitCode, frame.ExitCode) {
+					events = append(events, Event{Type: EventFrame, Screenplay: screenplay.Name, Scene: scene.Name, Frame: &scene.Frames[i]})
+				}
+			}
+		}
+	}
+	return events
+}
+
+func sameExitCode(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// playEvents streams updates of the Play as server-sent events. Output of
+// containers executing frames is streamed as well if the logs parameter is set.
+func (s *Server) playEvents(w http.ResponseWriter, r *http.Request) {
+	namespace, name := chi.URLParam(r, "namespace"), chi.URLParam(r, "play")
+	if !s.authorize(w, r, kuberikResource("get", "plays", namespace, name)) {
+		return
+	}
+	withLogs := r.URL.Query().Get("logs") == "true"
+	if withLogs && !s.authorize(w, r, authorizationv1.ResourceAttributes{Verb: "get", Resource: "pods", Subresource: "log", Namespace: namespace}) {
+		return
+	}
+	if s.hub == nil {
+		writeError(w, http.StatusNotImplemented, "Play events aren't served")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming isn't supported")
+		return
+	}
+	play := &corev1alpha1.Play{}
+	if err := s.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, play); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	sub, tree := s.hub.subscribe(play)
+	defer s.hub.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	writeEvent(w, string(EventSnapshot), Event{Version: EventsVersion, Type: EventSnapshot, Namespace: namespace, Play: name, Tree: &tree})
+	flusher.Flush()
+
+	lines := make(chan LogLine)
+	followed := make(map[string]bool)
+	// pending is set while Pods are waiting to start, so they're checked again
+	var pending <-chan time.Time
+	playSelector := labels.SelectorFromSet(kuberikRuntime.PlaySelector(play)).String()
+	// followPods follows output of Pods matching the selector which aren't followed yet
+	followPods := func(selector string) {
+		pods, err := s.kube.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			writeEvent(w, string(EventLog), Event{Version: EventsVersion, Type: EventLog, Namespace: namespace, Play: name, Log: &LogLine{
+				Line: fmt.Sprintf("Failed to list Pods: %s", err),
+			}})
+			return
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if followed[pod.Name] {
+				continue
+			}
+			if pod.Status.Phase == corev1.PodPending {
+				pending = time.After(pendingPodsInterval)
+				continue
+			}
+			followed[pod.Name] = true
+			for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+				go s.followLogs(r, pod, container.Name, lines)
+			}
+		}
+	}
+	if withLogs {
+		followPods(playSelector)
+	}
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+	for {
+		select {
+		case event, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			writeEvent(w, string(event.Type), event)
+			if event.Type == EventDeleted {
+				flusher.Flush()
+				return
+			}
+			if withLogs && event.Type == EventExecution {
+				followPods(fmt.Sprintf("job-name=%s", event.Execution.Job))
+			}
+		case line := <-lines:
+			writeEvent(w, string(EventLog), Event{Version: EventsVersion, Type: EventLog, Namespace: namespace, Play: name, Log: &line})
+		case <-pending:
+			pending = nil
+			followPods(playSelector)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
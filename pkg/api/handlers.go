@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/go-chi/chi"
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/playtree"
+	"github.com/kuberik/kuberik/pkg/screener"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// apiTrigger is the trigger of Plays created through the API
+const apiTrigger = "api"
+
+// TriggerRequest is the body of a request triggering a Play of a Movie
+type TriggerRequest struct {
+	// Vars override vars of the Movie
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// LogLine is a line of output of a container sent by the logs endpoint
+type LogLine struct {
+	// Frame is the ID of the frame the container executes
+	Frame     string `json:"frame,omitempty"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Line      string `json:"line"`
+}
+
+func (s *Server) listMovies(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	if !s.authorize(w, r, kuberikResource("list", "movies", namespace, "")) {
+		return
+	}
+	movies := &corev1alpha1.MovieList{}
+	if err := s.client.List(r.Context(), movies, client.InNamespace(namespace)); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, movies)
+}
+
+func (s *Server) triggerPlay(w http.ResponseWriter, r *http.Request) {
+	namespace, name := chi.URLParam(r, "namespace"), chi.URLParam(r, "movie")
+	// Play is created from the Movie, so the caller needs to be able to read it
+	if !s.authorize(w, r, kuberikResource("create", "plays", namespace, "")) || !s.authorize(w, r, kuberikResource("get", "movies", namespace, name)) {
+		return
+	}
+	request := TriggerRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request: %s", err)
+			return
+		}
+	}
+	movie := &corev1alpha1.Movie{}
+	if err := s.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, movie); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	var vars corev1alpha1.Vars
+	for k, v := range request.Vars {
+		vars = append(vars, corev1alpha1.Var{Name: k, Value: v})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	play := screener.NewPlay(movie, "", vars)
+	play.Annotations[screener.TriggerAnnotation] = apiTrigger
+	if err := s.client.Create(r.Context(), play); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, playtree.New(play))
+}
+
+func (s *Server) getPlay(w http.ResponseWriter, r *http.Request) {
+	namespace, name := chi.URLParam(r, "namespace"), chi.URLParam(r, "play")
+	if !s.authorize(w, r, kuberikResource("get", "plays", namespace, name)) {
+		return
+	}
+	play := &corev1alpha1.Play{}
+	if err := s.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, play); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, playtree.New(play))
+}
+
+func (s *Server) cancelPlay(w http.ResponseWriter, r *http.Request) {
+	namespace, name := chi.URLParam(r, "namespace"), chi.URLParam(r, "play")
+	if !s.authorize(w, r, kuberikResource("patch", "plays", namespace, name)) {
+		return
+	}
+	play := &corev1alpha1.Play{}
+	if err := s.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, play); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if play.Status.CompletionTime != nil {
+		writeError(w, http.StatusConflict, "Play %s already finished", name)
+		return
+	}
+	patch := client.ConstantPatch(types.MergePatchType, []byte(`{"spec":{"cancel":true}}`))
+	if err := s.client.Patch(r.Context(), play, patch); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, playtree.New(play))
+}
+
+// frameLogs streams output of containers of the last execution of the frame as
+// server-sent events. Lines are sent as log events and the stream finishes
+// with an end event once all containers stopped.
+func (s *Server) frameLogs(w http.ResponseWriter, r *http.Request) {
+	namespace, name, frameName := chi.URLParam(r, "namespace"), chi.URLParam(r, "play"), chi.URLParam(r, "frame")
+	resource := authorizationv1.ResourceAttributes{Verb: "get", Resource: "pods", Subresource: "log", Namespace: namespace}
+	if !s.authorize(w, r, kuberikResource("get", "plays", namespace, name)) || !s.authorize(w, r, resource) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming isn't supported")
+		return
+	}
+	play := &corev1alpha1.Play{}
+	if err := s.client.Get(r.Context(), types.NamespacedName{Namespace: namespace, Name: name}, play); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	frameID, ok := findFrame(play, frameName)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Frame %s of Play %s not found", frameName, name)
+		return
+	}
+	pod, err := s.lastPod(play, frameID)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if pod == nil {
+		writeError(w, http.StatusNotFound, "Frame %s of Play %s has no executions", frameName, name)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	lines := make(chan LogLine)
+	var wg sync.WaitGroup
+	for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		wg.Add(1)
+		go func(container string) {
+			defer wg.Done()
+			s.followLogs(r, pod, container, lines)
+		}(container.Name)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+	for line := range lines {
+		writeEvent(w, "log", line)
+		flusher.Flush()
+	}
+	writeEvent(w, "end", struct{}{})
+	flusher.Flush()
+}
+
+// followLogs sends lines of output of the container until it stops or the request is done
+func (s *Server) followLogs(r *http.Request, pod *corev1.Pod, container string, lines chan<- LogLine) {
+	frame := pod.Labels[kuberikRuntime.FrameLabel]
+	stream, err := s.logs(r.Context(), pod, container)
+	if err != nil {
+		lines <- LogLine{Frame: frame, Pod: pod.Name, Container: container, Line: fmt.Sprintf("Failed to get logs: %s", err)}
+		return
+	}
+	defer stream.Close()
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		select {
+		case lines <- LogLine{Frame: frame, Pod: pod.Name, Container: container, Line: scanner.Text()}:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// lastPod returns the newest Pod of executions of the frame, or nil if there are none
+func (s *Server) lastPod(play *corev1alpha1.Play, frameID string) (*corev1.Pod, error) {
+	selector := kuberikRuntime.PlaySelector(play)
+	selector[kuberikRuntime.FrameLabel] = kubeutils.LabelValue(frameID)
+	pods, err := s.kube.CoreV1().Pods(play.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil || len(pods.Items) == 0 {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+	return &pods.Items[0], nil
+}
+
+// findFrame returns the ID of the frame of the Play with the name or ID
+func findFrame(play *corev1alpha1.Play, frame string) (string, bool) {
+	for _, screenplay := range playtree.New(play).Screenplays {
+		for _, scene := range screenplay.Scenes {
+			for _, f := range scene.Frames {
+				if f.Name == frame || f.ID == frame {
+					return f.ID, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	body, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, body)
+}
@@ -0,0 +1,146 @@
+// Package api serves a REST API of Movies and Plays, so kuberik can be driven
+// by other tools without access to the cluster. Requests are authenticated with
+// TokenReviews and authorized with SubjectAccessReviews, so RBAC of the callers
+// still applies. Updates of Plays are streamed to the web dashboard served with
+// the API.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/cors"
+	"github.com/kuberik/kuberik/pkg/api/ui"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Prefix is the path prefix of all endpoints of the API
+const Prefix = "/api/v1"
+
+// time in which requests need to finish when the server shuts down
+const shutdownTimeout = 10 * time.Second
+
+// Server serves the API. It acts on Movies and Plays with its own client once
+// the caller is authorized.
+type Server struct {
+	// Address is the address the server listens on
+	Address string
+	// AllowedOrigins are origins from which the API can be called by browsers.
+	// Cross-origin requests aren't allowed if it's empty.
+	AllowedOrigins []string
+	// UI is the bundle of the web dashboard served outside of the API prefix.
+	// The dashboard isn't served if it's nil.
+	UI http.FileSystem
+
+	client client.Client
+	kube   kubernetes.Interface
+	// logs opens the output stream of the container of the Pod
+	logs func(ctx context.Context, pod *corev1.Pod, container string) (io.ReadCloser, error)
+	// hub broadcasts updates of Plays to event streams once informers are watched
+	hub *hub
+}
+
+// NewServer creates a Server which acts on objects with the client and
+// reviews tokens and access of callers with the Kubernetes clientset
+func NewServer(address string, c client.Client, kube kubernetes.Interface) *Server {
+	s := &Server{Address: address, UI: ui.FileSystem, client: c, kube: kube}
+	s.logs = s.followContainer
+	return s
+}
+
+// followContainer follows the output of the container of the Pod until it stops
+func (s *Server) followContainer(ctx context.Context, pod *corev1.Pod, container string) (io.ReadCloser, error) {
+	return s.kube.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Follow:    true,
+	}).Context(ctx).Stream()
+}
+
+// Handler returns the router of the API
+func (s *Server) Handler() http.Handler {
+	r := chi.NewRouter()
+	if len(s.AllowedOrigins) > 0 {
+		r.Use(cors.New(cors.Options{
+			AllowedOrigins: s.AllowedOrigins,
+			AllowedMethods: []string{http.MethodGet, http.MethodPost},
+			AllowedHeaders: []string{"Authorization", "Content-Type"},
+		}).Handler)
+	}
+	r.Route(Prefix+"/namespaces/{namespace}", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(s.authenticate(false))
+			r.Get("/movies", s.listMovies)
+			r.Post("/movies/{movie}/plays", s.triggerPlay)
+			r.Get("/plays/{play}", s.getPlay)
+			r.Post("/plays/{play}/cancel", s.cancelPlay)
+		})
+		// Streams are read by EventSource of browsers, which can't set headers
+		r.Group(func(r chi.Router) {
+			r.Use(s.authenticate(true))
+			r.Get("/plays/{play}/events", s.playEvents)
+			r.Get("/plays/{play}/frames/{frame}/logs", s.frameLogs)
+		})
+	})
+	if s.UI != nil {
+		r.Handle("/*", http.FileServer(s.UI))
+	}
+	return r
+}
+
+// Start serves the API until the stop channel is closed
+func (s *Server) Start(stop <-chan struct{}) error {
+	server := &http.Server{Addr: s.Address, Handler: s.Handler()}
+	errs := make(chan error, 1)
+	go func() {
+		log.Infof("Serving API on %s", s.Address)
+		errs <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
+}
+
+// NeedLeaderElection returns false, since the API is served by all replicas
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// apiError is the body of responses of failed requests
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Errorf("Failed to write response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, apiError{Message: fmt.Sprintf(format, args...)})
+}
+
+// writeAPIError responds with the status of the error returned by the Kubernetes API
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if statusErr, ok := err.(errors.APIStatus); ok && statusErr.Status().Code != 0 {
+		status = int(statusErr.Status().Code)
+	}
+	writeError(w, status, "%s", err)
+}
@@ -0,0 +1,152 @@
+package ui
+
+// files of the bundle. The dashboard renders the live graph of a Play from
+// events of the Play event stream.
+var files = map[string]string{
+	"/index.html": indexHTML,
+	"/app.js":     appJS,
+	"/style.css":  styleCSS,
+}
+
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Kuberik</title>
+  <link rel="stylesheet" href="style.css">
+</head>
+<body>
+  <form id="connect">
+    <input id="namespace" placeholder="Namespace" value="default" required>
+    <input id="play" placeholder="Play" required>
+    <input id="token" type="password" placeholder="Token" required>
+    <button type="submit">Watch</button>
+  </form>
+  <h1 id="title"></h1>
+  <div id="graph"></div>
+  <pre id="logs"></pre>
+  <script src="app.js"></script>
+</body>
+</html>
+`
+
+const appJS = `"use strict";
+
+// Version of the event model the dashboard understands
+const EVENTS_VERSION = "v1";
+
+let source = null;
+
+function frameElement(id) {
+  return document.getElementById("frame-" + id);
+}
+
+function setStatus(element, status) {
+  element.className = element.className.replace(/ status-\w+/g, "") + " status-" + status.toLowerCase();
+}
+
+function render(tree) {
+  const graph = document.getElementById("graph");
+  graph.innerHTML = "";
+  for (const screenplay of tree.screenplays) {
+    const row = document.createElement("div");
+    row.className = "screenplay";
+    for (const scene of screenplay.scenes) {
+      const column = document.createElement("div");
+      column.id = "scene-" + screenplay.name + "/" + scene.name;
+      column.className = "scene";
+      column.innerHTML = "<h2></h2>";
+      column.firstChild.textContent = scene.name;
+      setStatus(column, scene.status);
+      for (const frame of scene.frames) {
+        const box = document.createElement("div");
+        box.id = "frame-" + frame.id;
+        box.className = "frame";
+        box.textContent = frame.name;
+        setStatus(box, frame.status);
+        column.appendChild(box);
+      }
+      row.appendChild(column);
+    }
+    graph.appendChild(row);
+  }
+  setPhase(tree.namespace + "/" + tree.name, tree.phase);
+}
+
+function setPhase(play, phase) {
+  document.getElementById("title").textContent = play + (phase ? " " + phase : "");
+}
+
+function log(line) {
+  const logs = document.getElementById("logs");
+  const frame = frameElement(line.frame);
+  logs.textContent += "[" + (frame ? frame.textContent : line.pod) + "] " + line.line + "\n";
+  logs.scrollTop = logs.scrollHeight;
+}
+
+function handle(message) {
+  const event = JSON.parse(message.data);
+  if (event.version !== EVENTS_VERSION) {
+    console.warn("Unsupported event version", event.version);
+    return;
+  }
+  switch (event.type) {
+    case "snapshot":
+      render(event.tree);
+      break;
+    case "phase":
+      setPhase(event.namespace + "/" + event.play, event.phase);
+      break;
+    case "scene": {
+      const column = document.getElementById("scene-" + event.screenplay + "/" + event.scene);
+      if (column) setStatus(column, event.status);
+      break;
+    }
+    case "frame": {
+      const box = frameElement(event.frame.id);
+      if (box) setStatus(box, event.frame.status);
+      break;
+    }
+    case "execution": {
+      const box = frameElement(event.execution.frame);
+      if (box) box.classList.toggle("active", event.execution.active > 0);
+      break;
+    }
+    case "log":
+      log(event.log);
+      break;
+    case "deleted":
+      setPhase(event.namespace + "/" + event.play, "Deleted");
+      source.close();
+      break;
+  }
+}
+
+document.getElementById("connect").addEventListener("submit", function (e) {
+  e.preventDefault();
+  if (source) source.close();
+  document.getElementById("logs").textContent = "";
+  const namespace = encodeURIComponent(document.getElementById("namespace").value);
+  const play = encodeURIComponent(document.getElementById("play").value);
+  const token = encodeURIComponent(document.getElementById("token").value);
+  source = new EventSource("api/v1/namespaces/" + namespace + "/plays/" + play + "/events?logs=true&access_token=" + token);
+  for (const type of ["snapshot", "phase", "scene", "frame", "execution", "log", "deleted"]) {
+    source.addEventListener(type, handle);
+  }
+});
+`
+
+const styleCSS = `body { font-family: sans-serif; margin: 1em; }
+#graph { margin: 1em 0; }
+.screenplay { display: flex; gap: 2em; margin-bottom: 1em; }
+.scene { border-top: 4px solid #ccc; padding-top: .5em; min-width: 10em; }
+.scene h2 { font-size: 1em; margin: 0 0 .5em; }
+.frame { border: 1px solid #ccc; border-radius: 4px; padding: .5em; margin-bottom: .5em; }
+.frame.active { font-weight: bold; }
+.status-pending { border-color: #ccc; color: #888; }
+.status-running { border-color: #2b7cd3; }
+.status-succeeded { border-color: #2da44e; }
+.status-failed { border-color: #cf222e; }
+.status-skipped { border-color: #ccc; color: #888; text-decoration: line-through; }
+#logs { background: #111; color: #eee; padding: 1em; height: 20em; overflow: auto; }
+`
@@ -0,0 +1,115 @@
+// Package ui holds the static bundle of the web dashboard. Files of the bundle
+// are compiled into the binary, so the dashboard is served without files on
+// the disk of the operator.
+package ui
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileSystem serves files of the bundle
+var FileSystem http.FileSystem = fileSystem(files)
+
+// fileSystem is a flat directory of files keyed by absolute paths
+type fileSystem map[string]string
+
+// Open opens the file or the root directory of the file system
+func (fs fileSystem) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		var entries []os.FileInfo
+		for filePath, content := range fs {
+			entries = append(entries, fileInfo{name: strings.TrimPrefix(filePath, "/"), size: int64(len(content))})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		return &file{info: fileInfo{name: "/", dir: true}, entries: entries}, nil
+	}
+	content, ok := fs[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &file{
+		Reader: bytes.NewReader([]byte(content)),
+		info:   fileInfo{name: path.Base(name), size: int64(len(content))},
+	}, nil
+}
+
+// file is an open file or directory of a fileSystem
+type file struct {
+	*bytes.Reader
+	info    fileInfo
+	entries []os.FileInfo
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.info.dir {
+		return 0, io.EOF
+	}
+	return f.Reader.Read(p)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.info.dir {
+		return 0, nil
+	}
+	return f.Reader.Seek(offset, whence)
+}
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.info.dir {
+		return nil, os.ErrInvalid
+	}
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.entries) {
+		count = len(f.entries)
+	}
+	entries := f.entries[:count]
+	f.entries = f.entries[count:]
+	return entries, nil
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i fileInfo) Name() string { return i.name }
+
+func (i fileInfo) Size() int64 { return i.size }
+
+func (i fileInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ModTime is zero, so responses aren't cached by modification time
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+
+func (i fileInfo) IsDir() bool { return i.dir }
+
+func (i fileInfo) Sys() interface{} { return nil }
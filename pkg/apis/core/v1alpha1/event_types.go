@@ -9,16 +9,34 @@ import (
 
 // EventSpec defines the desired state of Event
 type EventSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
-	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+	// Type of the event, e.g. PlayComplete.
+	Type string `json:"type"`
+	// Source identifies what published the event, e.g. name of a Movie.
+	Source string `json:"source"`
+	// Data is a JSON payload of the event.
+	// +optional
+	Data string `json:"data,omitempty"`
+	// TTLSecondsAfterProcessed limits the lifetime of an Event after it has been
+	// processed by screeners. Defaults to one hour.
+	// +optional
+	TTLSecondsAfterProcessed *int32 `json:"ttlSecondsAfterProcessed,omitempty"`
 }
 
 // EventStatus defines the observed state of Event
 type EventStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
-	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+	// ProcessedTime is the time when the event was delivered to screeners.
+	// +optional
+	ProcessedTime *metav1.Time `json:"processedTime,omitempty"`
+}
+
+// EventPublish defines an Event to be published.
+type EventPublish struct {
+	// Type of the published event.
+	Type string `json:"type"`
+	// Data is a JSON payload of the event. References to vars in form of
+	// $(VAR_NAME) are expanded.
+	// +optional
+	Data string `json:"data,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
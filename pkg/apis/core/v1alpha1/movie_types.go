@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -16,6 +17,13 @@ type MovieSpec struct {
 	FailedJobsHistoryLimit int `json:"failedJobsHistoryLimit"`
 	// +optional
 	SuccessfulJobsHistoryLimit int `json:"successfulJobsHistoryLimit"`
+	// Triggers start the Movie when a Play of an upstream Movie finishes.
+	// +optional
+	Triggers []MovieTrigger `json:"triggers,omitempty"`
+	// Operator makes the Movie an operator which plays the screenplay on every
+	// change of a custom resource.
+	// +optional
+	Operator *MovieOperator `json:"operator,omitempty"`
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
@@ -27,9 +35,40 @@ type PlayTemplate struct {
 	Spec              PlaySpec `json:"spec,omitempty"`
 }
 
+// MovieTrigger starts a Movie when a Play of another Movie finishes.
+type MovieTrigger struct {
+	// Movie is the name of the upstream Movie.
+	Movie string `json:"movie"`
+	// Phases of the upstream Play which trigger the Movie. Defaults to Complete.
+	// +optional
+	Phases []PlayPhaseType `json:"phases,omitempty"`
+	// Vars is a list of names of vars forwarded from the upstream Play.
+	// +optional
+	Vars []string `json:"vars,omitempty"`
+}
+
+// MovieOperator defines a custom resource reconciled by a Movie.
+type MovieOperator struct {
+	// Group of the custom resource. Defaults to extensions.kuberik.io. Other
+	// groups need to be granted to the kuberik ClusterRole.
+	// +optional
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	// Schema is the OpenAPI v3 schema used to validate the custom resource.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Schema *runtime.RawExtension `json:"schema,omitempty"`
+}
+
 // MovieStatus defines the observed state of Movie
 // +k8s:openapi-gen=true
 type MovieStatus struct {
+	// Triggers maps names of upstream Movies of the triggers to the time when
+	// the trigger was first observed. Plays of upstream Movies created before
+	// don't trigger the Movie.
+	// +optional
+	Triggers map[string]metav1.Time `json:"triggers,omitempty"`
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
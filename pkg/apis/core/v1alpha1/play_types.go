@@ -17,6 +17,13 @@ type PlaySpec struct {
 	Screenplays          []Screenplay                   `json:"screenplays"`
 	VolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
 	Vars                 Vars                           `json:"vars,omitempty"`
+	// Input is a JSON payload from which vars can be selected with inputRef.
+	// +optional
+	Input string `json:"input,omitempty"`
+	// Cancel stops the Play. Running executions are cancelled and the Play
+	// finishes in phase Failed with the Cancelled condition.
+	// +optional
+	Cancel bool `json:"cancel,omitempty"`
 }
 
 // PlayStatus defines the observed state of Play
@@ -25,11 +32,110 @@ type PlayStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
-	Frames             map[string]int    `json:"frames,omitempty"`
-	Phase              PlayPhaseType     `json:"phase,omitempty"`
-	Runner             string            `json:"runner,omitempty"`
+	Frames map[string]int `json:"frames,omitempty"`
+	// FrameFailures describes why frames failed, keyed by frame ID
+	// +optional
+	FrameFailures map[string]FrameFailure `json:"frameFailures,omitempty"`
+	Phase         PlayPhaseType           `json:"phase,omitempty"`
+	// Runner is the ID of the operator replica holding the lease of the Play
+	Runner string `json:"runner,omitempty"`
+	// RenewTime is the time when the runner last renewed its lease of the Play.
+	// Other replicas take the Play over once the lease expires.
+	// +optional
+	RenewTime          *metav1.Time      `json:"renewTime,omitempty"`
 	ProvisionedVolumes map[string]string `json:"provisionedVolumes,omitempty"`
 	VarsConfigMap      string            `json:"varsConfigMap,omitempty"`
+	// ObservedGeneration is the generation of the Play last processed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the latest observations of the state of the Play
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []PlayCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// StartTime is the time when the Play started running
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time when the Play finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// PlayConditionType is a type of a condition of a Play
+type PlayConditionType string
+
+// These are valid conditions of a Play.
+const (
+	// PlayConditionProvisioned means objects of the Play, such as the vars
+	// ConfigMap and volumes, are provisioned
+	PlayConditionProvisioned PlayConditionType = "Provisioned"
+	// PlayConditionRunning means frames of the Play are being played
+	PlayConditionRunning PlayConditionType = "Running"
+	// PlayConditionSucceeded means the Play finished and all of its scenes succeeded
+	PlayConditionSucceeded PlayConditionType = "Succeeded"
+	// PlayConditionCancelled means the Play was stopped before it finished
+	PlayConditionCancelled PlayConditionType = "Cancelled"
+	// PlayConditionPublished means the Event announcing that the Play finished was published
+	PlayConditionPublished PlayConditionType = "Published"
+)
+
+// PlayCondition describes the state of a Play at a certain point
+// +k8s:openapi-gen=true
+type PlayCondition struct {
+	// Type of the condition
+	Type PlayConditionType `json:"type"`
+	// Status of the condition, one of True, False or Unknown
+	Status corev1.ConditionStatus `json:"status"`
+	// ObservedGeneration is the generation of the Play the condition was set for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastTransitionTime is the time when the condition last changed its status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Reason is a brief CamelCase reason for the last transition of the condition
+	Reason string `json:"reason"`
+	// Message is a human readable description of the last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Condition returns the condition of the type or nil if it isn't set
+func (s *PlayStatus) Condition(conditionType PlayConditionType) *PlayCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or replaces the condition of the same type. Transition time
+// of the existing condition is kept if its status didn't change, and it's set
+// to the current time if it's not set on the condition.
+func (s *PlayStatus) SetCondition(condition PlayCondition) {
+	existing := s.Condition(condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, condition)
+		return
+	}
+	if existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	} else if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	*existing = condition
+}
+
+// FrameFailure describes why execution of a frame failed
+// +k8s:openapi-gen=true
+type FrameFailure struct {
+	// Reason is a brief CamelCase cause of the failure, such as OOMKilled or ImagePullBackOff
+	Reason string `json:"reason"`
+	// Message is a human readable description of the failure
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // PlayPhaseType defines the phase of a Play
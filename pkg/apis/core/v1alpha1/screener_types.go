@@ -9,16 +9,94 @@ import (
 
 // ScreenerSpec defines the desired state of Screener
 type ScreenerSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
-	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+	// Movie is the name of the Movie from which Plays are created.
+	Movie string `json:"movie"`
+	// Git triggers Plays when new commits show up on a git remote.
+	// +optional
+	Git *GitScreener `json:"git,omitempty"`
+	// Resource triggers Plays when Kubernetes resources change.
+	// +optional
+	Resource *ResourceScreener `json:"resource,omitempty"`
+	// Event triggers Plays when matching Events are published.
+	// +optional
+	Event *EventScreener `json:"event,omitempty"`
+}
+
+// GitScreener polls a git remote for new revisions of matching refs.
+type GitScreener struct {
+	// Repository is the URL of the git remote.
+	Repository string `json:"repository"`
+	// Branches is a list of glob patterns for branches which trigger Plays.
+	// +optional
+	Branches []string `json:"branches,omitempty"`
+	// Tags is a list of glob patterns for tags which trigger Plays.
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+	// PollInterval defines how often the remote is checked. Defaults to one minute.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+}
+
+// ResourceScreener watches Kubernetes resources of a kind.
+type ResourceScreener struct {
+	// APIVersion of the watched resources, e.g. apps/v1.
+	APIVersion string `json:"apiVersion"`
+	// Kind of the watched resources, e.g. Deployment.
+	Kind string `json:"kind"`
+	// Events is a list of events which trigger Plays. Defaults to all events.
+	// +optional
+	Events []ResourceEventType `json:"events,omitempty"`
+}
+
+// ResourceEventType defines a change of a resource watched by a screener.
+type ResourceEventType string
+
+// These are valid resource event types.
+const (
+	// ResourceCreated means the resource has been created.
+	ResourceCreated ResourceEventType = "Created"
+	// ResourceUpdated means the spec of the resource has changed.
+	ResourceUpdated ResourceEventType = "Updated"
+	// ResourceDeleted means the resource has been deleted.
+	ResourceDeleted ResourceEventType = "Deleted"
+)
+
+// EventScreener subscribes to Events. Filters are glob patterns and an Event
+// needs to match one pattern of every filter which is set.
+type EventScreener struct {
+	// Types of the events which trigger Plays.
+	// +optional
+	Types []string `json:"types,omitempty"`
+	// Sources of the events which trigger Plays.
+	// +optional
+	Sources []string `json:"sources,omitempty"`
 }
 
 // ScreenerStatus defines the observed state of Screener
 type ScreenerStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
-	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+	// Git holds the state of a git screener.
+	// +optional
+	Git *GitScreenerStatus `json:"git,omitempty"`
+	// Resource holds the state of a resource screener.
+	// +optional
+	Resource *ResourceScreenerStatus `json:"resource,omitempty"`
+}
+
+// GitScreenerStatus holds the last seen revisions of a git remote.
+type GitScreenerStatus struct {
+	// Revisions maps matching refs to the last commit seen on them.
+	Revisions map[string]string `json:"revisions,omitempty"`
+	// LastPollTime is the time when the remote was last checked.
+	// +optional
+	LastPollTime *metav1.Time `json:"lastPollTime,omitempty"`
+}
+
+// ResourceScreenerStatus holds the last seen generations of watched resources.
+type ResourceScreenerStatus struct {
+	// Generations maps UIDs of resources to the last generation seen by the
+	// screener, so that events aren't lost or repeated when the operator
+	// restarts.
+	Generations map[string]int64 `json:"generations,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -114,6 +114,12 @@ type Frame struct {
 	SkipCondition Condition `json:"skipCondition,omitempty"`
 	Action        *Exec     `json:"action,omitempty"`
 	Story         *string   `json:"story,omitempty"`
+	// Publish defines an Event which is published when the frame succeeds.
+	Publish *EventPublish `json:"publish,omitempty"`
+	// Runtime is the name of the scheduler executing the frame. The default
+	// scheduler of the engine is used if it's empty.
+	// +optional
+	Runtime string `json:"runtime,omitempty"`
 }
 
 // Exec Represents a running container
@@ -127,5 +133,7 @@ func (f *Frame) Copy() Frame {
 		Action:       f.Action.DeepCopy(),
 		IgnoreErrors: f.IgnoreErrors,
 		Copies:       f.Copies,
+		Publish:      f.Publish.DeepCopy(),
+		Runtime:      f.Runtime,
 	}
 }
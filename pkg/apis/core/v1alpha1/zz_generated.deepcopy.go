@@ -7,6 +7,7 @@ package v1alpha1
 import (
 	v1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -43,8 +44,8 @@ func (in *Event) DeepCopyInto(out *Event) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -99,9 +100,56 @@ func (in *EventList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventPublish) DeepCopyInto(out *EventPublish) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventPublish.
+func (in *EventPublish) DeepCopy() *EventPublish {
+	if in == nil {
+		return nil
+	}
+	out := new(EventPublish)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventScreener) DeepCopyInto(out *EventScreener) {
+	*out = *in
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventScreener.
+func (in *EventScreener) DeepCopy() *EventScreener {
+	if in == nil {
+		return nil
+	}
+	out := new(EventScreener)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventSpec) DeepCopyInto(out *EventSpec) {
 	*out = *in
+	if in.TTLSecondsAfterProcessed != nil {
+		in, out := &in.TTLSecondsAfterProcessed, &out.TTLSecondsAfterProcessed
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -118,6 +166,10 @@ func (in *EventSpec) DeepCopy() *EventSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventStatus) DeepCopyInto(out *EventStatus) {
 	*out = *in
+	if in.ProcessedTime != nil {
+		in, out := &in.ProcessedTime, &out.ProcessedTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -157,6 +209,11 @@ func (in *Frame) DeepCopyInto(out *Frame) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Publish != nil {
+		in, out := &in.Publish, &out.Publish
+		*out = new(EventPublish)
+		**out = **in
+	}
 	return
 }
 
@@ -170,6 +227,80 @@ func (in *Frame) DeepCopy() *Frame {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FrameFailure) DeepCopyInto(out *FrameFailure) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrameFailure.
+func (in *FrameFailure) DeepCopy() *FrameFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(FrameFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitScreener) DeepCopyInto(out *GitScreener) {
+	*out = *in
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitScreener.
+func (in *GitScreener) DeepCopy() *GitScreener {
+	if in == nil {
+		return nil
+	}
+	out := new(GitScreener)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitScreenerStatus) DeepCopyInto(out *GitScreenerStatus) {
+	*out = *in
+	if in.Revisions != nil {
+		in, out := &in.Revisions, &out.Revisions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastPollTime != nil {
+		in, out := &in.LastPollTime, &out.LastPollTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitScreenerStatus.
+func (in *GitScreenerStatus) DeepCopy() *GitScreenerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GitScreenerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InputFieldSelector) DeepCopyInto(out *InputFieldSelector) {
 	*out = *in
@@ -192,7 +323,7 @@ func (in *Movie) DeepCopyInto(out *Movie) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -247,10 +378,43 @@ func (in *MovieList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MovieOperator) DeepCopyInto(out *MovieOperator) {
+	*out = *in
+	if in.Schema != nil {
+		in, out := &in.Schema, &out.Schema
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MovieOperator.
+func (in *MovieOperator) DeepCopy() *MovieOperator {
+	if in == nil {
+		return nil
+	}
+	out := new(MovieOperator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MovieSpec) DeepCopyInto(out *MovieSpec) {
 	*out = *in
 	in.Template.DeepCopyInto(&out.Template)
+	if in.Triggers != nil {
+		in, out := &in.Triggers, &out.Triggers
+		*out = make([]MovieTrigger, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Operator != nil {
+		in, out := &in.Operator, &out.Operator
+		*out = new(MovieOperator)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -267,6 +431,13 @@ func (in *MovieSpec) DeepCopy() *MovieSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MovieStatus) DeepCopyInto(out *MovieStatus) {
 	*out = *in
+	if in.Triggers != nil {
+		in, out := &in.Triggers, &out.Triggers
+		*out = make(map[string]metav1.Time, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	return
 }
 
@@ -280,6 +451,32 @@ func (in *MovieStatus) DeepCopy() *MovieStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MovieTrigger) DeepCopyInto(out *MovieTrigger) {
+	*out = *in
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make([]PlayPhaseType, len(*in))
+		copy(*out, *in)
+	}
+	if in.Vars != nil {
+		in, out := &in.Vars, &out.Vars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MovieTrigger.
+func (in *MovieTrigger) DeepCopy() *MovieTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(MovieTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Play) DeepCopyInto(out *Play) {
 	*out = *in
@@ -308,6 +505,23 @@ func (in *Play) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlayCondition) DeepCopyInto(out *PlayCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlayCondition.
+func (in *PlayCondition) DeepCopy() *PlayCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(PlayCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlayList) DeepCopyInto(out *PlayList) {
 	*out = *in
@@ -388,6 +602,17 @@ func (in *PlayStatus) DeepCopyInto(out *PlayStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.FrameFailures != nil {
+		in, out := &in.FrameFailures, &out.FrameFailures
+		*out = make(map[string]FrameFailure, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RenewTime != nil {
+		in, out := &in.RenewTime, &out.RenewTime
+		*out = (*in).DeepCopy()
+	}
 	if in.ProvisionedVolumes != nil {
 		in, out := &in.ProvisionedVolumes, &out.ProvisionedVolumes
 		*out = make(map[string]string, len(*in))
@@ -395,6 +620,21 @@ func (in *PlayStatus) DeepCopyInto(out *PlayStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PlayCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -426,6 +666,50 @@ func (in *PlayTemplate) DeepCopy() *PlayTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceScreener) DeepCopyInto(out *ResourceScreener) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]ResourceEventType, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceScreener.
+func (in *ResourceScreener) DeepCopy() *ResourceScreener {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceScreener)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceScreenerStatus) DeepCopyInto(out *ResourceScreenerStatus) {
+	*out = *in
+	if in.Generations != nil {
+		in, out := &in.Generations, &out.Generations
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceScreenerStatus.
+func (in *ResourceScreenerStatus) DeepCopy() *ResourceScreenerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceScreenerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Scene) DeepCopyInto(out *Scene) {
 	*out = *in
@@ -467,8 +751,8 @@ func (in *Screener) DeepCopyInto(out *Screener) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -526,6 +810,21 @@ func (in *ScreenerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScreenerSpec) DeepCopyInto(out *ScreenerSpec) {
 	*out = *in
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitScreener)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resource != nil {
+		in, out := &in.Resource, &out.Resource
+		*out = new(ResourceScreener)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Event != nil {
+		in, out := &in.Event, &out.Event
+		*out = new(EventScreener)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -542,6 +841,16 @@ func (in *ScreenerSpec) DeepCopy() *ScreenerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScreenerStatus) DeepCopyInto(out *ScreenerStatus) {
 	*out = *in
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitScreenerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resource != nil {
+		in, out := &in.Resource, &out.Resource
+		*out = new(ResourceScreenerStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
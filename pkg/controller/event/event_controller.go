@@ -0,0 +1,134 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/eventbus"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_event")
+
+// Add creates a new Event Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileEvent{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("event-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource Event
+	err = c.Watch(&source.Kind{Type: &corev1alpha1.Event{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileEvent implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileEvent{}
+
+// ReconcileEvent reconciles a Event object
+type ReconcileEvent struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile delivers a new Event to all Screeners subscribed to it and deletes
+// processed Events once their TTL expires.
+func (r *ReconcileEvent) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling Event")
+
+	// Fetch the Event instance
+	instance := &corev1alpha1.Event{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	if instance.Status.ProcessedTime == nil {
+		if err := r.deliver(instance); err != nil {
+			return reconcile.Result{}, err
+		}
+		now := metav1.Now()
+		instance.Status.ProcessedTime = &now
+		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	expired, left := eventbus.Expired(instance, time.Now())
+	if expired {
+		reqLogger.Info("Deleting expired Event")
+		err := r.client.Delete(context.TODO(), instance)
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{RequeueAfter: left}, nil
+}
+
+func (r *ReconcileEvent) deliver(instance *corev1alpha1.Event) error {
+	screeners := &corev1alpha1.ScreenerList{}
+	if err := r.client.List(context.TODO(), screeners, client.InNamespace(instance.Namespace)); err != nil {
+		return err
+	}
+
+	for _, s := range screeners.Items {
+		if !screener.MatchEvent(s.Spec.Event, instance) {
+			continue
+		}
+		log.Info("Triggering Play", "Event", instance.Name, "Screener", s.Name)
+		movie := &corev1alpha1.Movie{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.Movie}, movie)
+		if err != nil {
+			return err
+		}
+		play := screener.NewPlay(movie, s.Name, screener.EventVars(instance))
+		// Deterministic name prevents duplicate Plays if delivery is retried
+		play.GenerateName = ""
+		play.Name = kubeutils.Name(s.Name, instance.Name)
+		play.Spec.Input = instance.Spec.Data
+		if err := r.client.Create(context.TODO(), play); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/kuberik/kuberik/pkg/controller/operator"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -49,11 +50,9 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	}
 	//err = c.Watch(&source.Informer{})
 
-	// TODO(user): Modify this to be the types you create that are owned by the primary resource
-	// Watch for changes to secondary resource Pods and requeue the owner Movie
-	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestForOwner{
-		IsController: true,
-		OwnerType:    &corev1alpha1.Movie{},
+	// Watch for finished Plays and requeue Movies triggered by them
+	err = c.Watch(&source.Kind{Type: &corev1alpha1.Play{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &downstreamMapper{client: mgr.GetClient()},
 	})
 	if err != nil {
 		return err
@@ -93,11 +92,32 @@ func (r *ReconcileMovie) Reconcile(request reconcile.Request) (reconcile.Result,
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			operator.RemoveMovie(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
+	if instance.Spec.Operator != nil {
+		if err := operator.AddMovie(r.mgr, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	} else {
+		operator.RemoveMovie(request.NamespacedName)
+	}
+
+	// Times when triggers were observed are recorded before triggering, so
+	// that Plays created before are never triggered.
+	if observeTriggers(instance, metav1.Now()) {
+		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	for _, trigger := range instance.Spec.Triggers {
+		if err := r.trigger(instance, trigger); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
 	return reconcile.Result{}, nil
 }
@@ -0,0 +1,82 @@
+package movie
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func upstreamPlay(name string, created time.Time) *corev1alpha1.Play {
+	return &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created),
+			Labels:            screener.MovieSelector("build"),
+		},
+		Status: corev1alpha1.PlayStatus{Phase: corev1alpha1.PlayComplete},
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	c := fake.NewFakeClientWithScheme(scheme,
+		&corev1alpha1.Movie{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "default", CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))},
+			Spec:       corev1alpha1.MovieSpec{Triggers: []corev1alpha1.MovieTrigger{{Movie: "build"}}},
+		},
+		// Finished after the Movie was created, but before its trigger was observed
+		upstreamPlay("build-old", now.Add(-time.Hour)),
+	)
+	r := &ReconcileMovie{client: c, scheme: scheme}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "deploy"}}
+	plays := func() []corev1alpha1.Play {
+		list := &corev1alpha1.PlayList{}
+		if err := c.List(context.TODO(), list, client.MatchingLabels(screener.MovieSelector("deploy"))); err != nil {
+			t.Fatal(err)
+		}
+		return list.Items
+	}
+
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatal(err)
+	}
+	if len(plays()) != 0 {
+		t.Errorf("Expected Plays created before the trigger was observed not to trigger the Movie, got %d Plays", len(plays()))
+	}
+
+	if err := c.Create(context.TODO(), upstreamPlay("build-new", now.Add(time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatal(err)
+	}
+	triggered := plays()
+	if len(triggered) != 1 || triggered[0].Annotations[screener.UpstreamAnnotation] != "build-new" {
+		t.Fatalf("Expected a single Play triggered by build-new, got %+v", triggered)
+	}
+
+	// Deleted Plays aren't triggered again
+	if err := c.Delete(context.TODO(), &triggered[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatal(err)
+	}
+	if len(plays()) != 0 {
+		t.Errorf("Expected deleted Play not to be triggered again, got %d Plays", len(plays()))
+	}
+}
@@ -0,0 +1,188 @@
+package movie
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ handler.Mapper = &downstreamMapper{}
+
+// downstreamMapper maps finished Plays to Movies which are triggered by them
+type downstreamMapper struct {
+	client client.Client
+}
+
+// Map implements handler.Mapper
+func (m *downstreamMapper) Map(o handler.MapObject) []reconcile.Request {
+	play, ok := o.Object.(*corev1alpha1.Play)
+	if !ok || !finished(play.Status.Phase) {
+		return nil
+	}
+	upstream := screener.MovieName(play)
+	if upstream == "" {
+		return nil
+	}
+
+	movies := &corev1alpha1.MovieList{}
+	if err := m.client.List(context.TODO(), movies, client.InNamespace(play.Namespace)); err != nil {
+		log.Error(err, "Failed to list downstream Movies")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, movie := range movies.Items {
+		for _, trigger := range movie.Spec.Triggers {
+			if trigger.Movie == upstream {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: movie.Namespace, Name: movie.Name},
+				})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+func finished(phase corev1alpha1.PlayPhaseType) bool {
+	return phase == corev1alpha1.PlayComplete || phase == corev1alpha1.PlayFailed || phase == corev1alpha1.PlayError
+}
+
+func triggeredBy(trigger corev1alpha1.MovieTrigger, phase corev1alpha1.PlayPhaseType) bool {
+	if len(trigger.Phases) == 0 {
+		return phase == corev1alpha1.PlayComplete
+	}
+	for _, p := range trigger.Phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// observeTriggers records the time when triggers of the Movie were first
+// observed and forgets triggers which were removed, so that a trigger added
+// again is observed again. It returns whether the status of the Movie changed.
+func observeTriggers(movie *corev1alpha1.Movie, now metav1.Time) bool {
+	changed := false
+	observed := make(map[string]bool)
+	for _, trigger := range movie.Spec.Triggers {
+		observed[trigger.Movie] = true
+		if _, ok := movie.Status.Triggers[trigger.Movie]; ok {
+			continue
+		}
+		if movie.Status.Triggers == nil {
+			movie.Status.Triggers = make(map[string]metav1.Time)
+		}
+		movie.Status.Triggers[trigger.Movie] = now
+		changed = true
+	}
+	for upstream := range movie.Status.Triggers {
+		if !observed[upstream] {
+			delete(movie.Status.Triggers, upstream)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// trigger creates a Play of the Movie for every upstream Play which was created
+// after the trigger was observed, finished in one of the trigger's phases and
+// hasn't triggered the Movie yet.
+func (r *ReconcileMovie) trigger(movie *corev1alpha1.Movie, trigger corev1alpha1.MovieTrigger) error {
+	ctx := context.TODO()
+	upstreamPlays := &corev1alpha1.PlayList{}
+	err := r.client.List(ctx, upstreamPlays, client.InNamespace(movie.Namespace), client.MatchingLabels(screener.MovieSelector(trigger.Movie)))
+	if err != nil {
+		return err
+	}
+	downstreamPlays := &corev1alpha1.PlayList{}
+	err = r.client.List(ctx, downstreamPlays, client.InNamespace(movie.Namespace), client.MatchingLabels(screener.MovieSelector(movie.Name)))
+	if err != nil {
+		return err
+	}
+	triggered := make(map[string]bool)
+	for _, p := range downstreamPlays.Items {
+		if screener.MovieName(&p) != movie.Name {
+			continue
+		}
+		if upstream, ok := p.Annotations[screener.UpstreamAnnotation]; ok {
+			triggered[upstream] = true
+		}
+	}
+
+	for i := range upstreamPlays.Items {
+		upstream := &upstreamPlays.Items[i]
+		if screener.MovieName(upstream) != trigger.Movie {
+			continue
+		}
+		// Plays created before the trigger was observed don't trigger the
+		// Movie, otherwise adding a trigger would replay the whole history of
+		// the upstream Movie.
+		observed := movie.Status.Triggers[trigger.Movie]
+		if upstream.CreationTimestamp.Before(&observed) {
+			continue
+		}
+		if triggered[upstream.Name] || !triggeredBy(trigger, upstream.Status.Phase) {
+			continue
+		}
+		// Name is derived from the upstream Play, so that a stale cache can't
+		// trigger the Movie twice for the same upstream Play. Upstream Plays
+		// remember the Plays they triggered, so that Plays which were pruned
+		// or deleted aren't triggered again.
+		name := fmt.Sprintf("%s-%s", movie.Name, upstream.Name)
+		downstream := Downstream(upstream)
+		if contains(downstream, name) {
+			continue
+		}
+
+		var vars corev1alpha1.Vars
+		for _, name := range trigger.Vars {
+			if value, err := upstream.Spec.Vars.Get(name); err == nil {
+				vars = append(vars, corev1alpha1.Var{Name: name, Value: value})
+			}
+		}
+		play := screener.NewPlay(movie, "", vars)
+		play.GenerateName = ""
+		play.Name = name
+		play.Annotations[screener.UpstreamAnnotation] = upstream.Name
+		log.Info("Triggering Play", "Movie", movie.Name, "Upstream", upstream.Name)
+		if err := r.client.Create(ctx, play); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+
+		if upstream.Annotations == nil {
+			upstream.Annotations = make(map[string]string)
+		}
+		upstream.Annotations[screener.DownstreamAnnotation] = strings.Join(append(downstream, play.Name), ",")
+		if err := r.client.Update(ctx, upstream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Downstream returns names of Plays triggered by the Play.
+func Downstream(play *corev1alpha1.Play) []string {
+	if downstream := play.Annotations[screener.DownstreamAnnotation]; downstream != "" {
+		return strings.Split(downstream, ",")
+	}
+	return nil
+}
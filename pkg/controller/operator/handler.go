@@ -0,0 +1,137 @@
+package operator
+
+import (
+	"encoding/json"
+	"sync"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// pendingEvent is an event which hasn't triggered Plays of all matching screeners yet
+type pendingEvent struct {
+	screener.ResourceEvent
+	uid        types.UID
+	generation int64
+	created    metav1.Time
+	// screeners are names of the screeners which still need to trigger a Play
+	// for the event. All matching screeners do if it's empty.
+	screeners []string
+}
+
+// includes checks if the screener still needs to trigger a Play for the event
+func (e pendingEvent) includes(screener string) bool {
+	if len(e.screeners) == 0 {
+		return true
+	}
+	for _, s := range e.screeners {
+		if s == screener {
+			return true
+		}
+	}
+	return false
+}
+
+// eventStore holds events which haven't been reconciled yet. Reconcile requests
+// only carry the name of the object, so the object itself needs to be kept
+// aside to be available after it has been deleted.
+type eventStore struct {
+	mu sync.Mutex
+	// watched is false when no screener watches the kind anymore. Events
+	// aren't recorded then, since nothing would reconcile them.
+	watched bool
+	events  map[types.NamespacedName][]pendingEvent
+}
+
+func newEventStore() *eventStore {
+	return &eventStore{watched: true, events: make(map[types.NamespacedName][]pendingEvent)}
+}
+
+// setWatched starts or stops recording events. Recorded events are dropped when stopped.
+func (s *eventStore) setWatched(watched bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watched = watched
+	if !watched {
+		s.events = make(map[types.NamespacedName][]pendingEvent)
+	}
+}
+
+func (s *eventStore) push(name types.NamespacedName, events ...pendingEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.watched {
+		return
+	}
+	s.events[name] = append(s.events[name], events...)
+}
+
+// retry puts back events which failed to be reconciled, ahead of the events
+// recorded in the meantime
+func (s *eventStore) retry(name types.NamespacedName, events ...pendingEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.watched {
+		return
+	}
+	s.events[name] = append(events, s.events[name]...)
+}
+
+func (s *eventStore) pop(name types.NamespacedName) []pendingEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.events[name]
+	delete(s.events, name)
+	return events
+}
+
+var _ handler.EventHandler = &enqueueResourceEvent{}
+
+// enqueueResourceEvent records every event in the store and enqueues a
+// reconcile request for the object. Objects listed by the informer when the
+// watch starts are reported as created, and the reconciler tells them apart by
+// generations recorded in the status of screeners.
+type enqueueResourceEvent struct {
+	events *eventStore
+}
+
+// Create implements EventHandler
+func (e *enqueueResourceEvent) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	e.add(corev1alpha1.ResourceCreated, evt.Meta, evt.Object, q)
+}
+
+// Update implements EventHandler
+func (e *enqueueResourceEvent) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	e.add(corev1alpha1.ResourceUpdated, evt.MetaNew, evt.ObjectNew, q)
+}
+
+// Delete implements EventHandler
+func (e *enqueueResourceEvent) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	e.add(corev1alpha1.ResourceDeleted, evt.Meta, evt.Object, q)
+}
+
+// Generic implements EventHandler
+func (e *enqueueResourceEvent) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {}
+
+func (e *enqueueResourceEvent) add(eventType corev1alpha1.ResourceEventType, meta metav1.Object, object runtime.Object, q workqueue.RateLimitingInterface) {
+	objectJSON, err := json.Marshal(object)
+	if err != nil {
+		log.Error(err, "Failed to marshal object", "Namespace", meta.GetNamespace(), "Name", meta.GetName())
+		return
+	}
+	name := types.NamespacedName{Namespace: meta.GetNamespace(), Name: meta.GetName()}
+	e.events.push(name, pendingEvent{ResourceEvent: screener.ResourceEvent{
+		Type:      eventType,
+		Name:      name.Name,
+		Namespace: name.Namespace,
+		Object:    objectJSON,
+	}, uid: meta.GetUID(), generation: meta.GetGeneration(), created: meta.GetCreationTimestamp()})
+	q.Add(reconcile.Request{NamespacedName: name})
+}
@@ -0,0 +1,56 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestEnqueueResourceEvent(t *testing.T) {
+	events := newEventStore()
+	h := &enqueueResourceEvent{events: events}
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+
+	created := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name: "config", Namespace: "default", UID: "1234", Generation: 2, CreationTimestamp: metav1.NewTime(time.Now()),
+	}}
+	h.Create(event.CreateEvent{Meta: created, Object: created}, q)
+	h.Delete(event.DeleteEvent{Meta: created, Object: created}, q)
+
+	if q.Len() != 1 {
+		t.Errorf("Expected a single reconcile request, got %d", q.Len())
+	}
+	recorded := events.pop(types.NamespacedName{Namespace: "default", Name: "config"})
+	if len(recorded) != 2 || recorded[0].Type != corev1alpha1.ResourceCreated || recorded[1].Type != corev1alpha1.ResourceDeleted {
+		t.Fatalf("Expected create and delete events, got %v", recorded)
+	}
+	if recorded[0].uid != created.UID || recorded[0].generation != created.Generation {
+		t.Errorf("Expected event to hold the UID and generation of the object, got %v", recorded[0])
+	}
+	if recorded[1].Name != "config" || len(recorded[1].Object) == 0 {
+		t.Errorf("Expected event to hold the deleted object, got %v", recorded[1])
+	}
+}
+
+func TestEventStoreRetry(t *testing.T) {
+	events := newEventStore()
+	name := types.NamespacedName{Namespace: "default", Name: "config"}
+	events.push(name, pendingEvent{ResourceEvent: screener.ResourceEvent{Type: corev1alpha1.ResourceUpdated}})
+	events.retry(name, pendingEvent{ResourceEvent: screener.ResourceEvent{Type: corev1alpha1.ResourceCreated}, screeners: []string{"b"}})
+
+	recorded := events.pop(name)
+	if len(recorded) != 2 || recorded[0].Type != corev1alpha1.ResourceCreated {
+		t.Fatalf("Expected retried event first, got %v", recorded)
+	}
+	if recorded[0].includes("a") || !recorded[0].includes("b") || !recorded[1].includes("a") {
+		t.Errorf("Expected retried event to include only screener b, got %v", recorded)
+	}
+}
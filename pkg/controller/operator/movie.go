@@ -0,0 +1,287 @@
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/screener"
+	apiextensionv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	operatorsLock sync.Mutex
+	// operators maps kinds of custom resources to Movies reconciling them
+	operators = make(map[schema.GroupVersionKind]types.NamespacedName)
+	// controllers holds kinds of custom resources which have a controller.
+	// Controllers can't be removed from the Manager, so controllers of
+	// released kinds stay idle until the kind is claimed again.
+	controllers = make(map[schema.GroupVersionKind]bool)
+)
+
+// AddMovie registers the custom resource defined by an operator Movie and adds
+// a controller which plays the Movie on every change of the resources to the Manager.
+// Kinds which the Movie reconciled before are released.
+func AddMovie(mgr manager.Manager, movie *corev1alpha1.Movie) error {
+	spec := movie.Spec.Operator
+	resource := newResourceFromGVK(schema.GroupVersionKind{Group: spec.Group, Version: spec.Version, Kind: spec.Kind})
+	movieName := types.NamespacedName{Namespace: movie.Namespace, Name: movie.Name}
+
+	operatorsLock.Lock()
+	defer operatorsLock.Unlock()
+	if owner, ok := operators[resource.GroupVersionKind]; ok && owner != movieName {
+		return fmt.Errorf("Kind %s is already reconciled by Movie %s", resource.GroupVersionKind, owner)
+	}
+
+	openAPISchema, err := operatorSchema(spec)
+	if err != nil {
+		return err
+	}
+	if controllers[resource.GroupVersionKind] {
+		// CRD is updated on every change of the Movie so that changes of the schema are applied
+		if err := registerCRD(mgr.GetConfig(), resource, openAPISchema); err != nil {
+			return err
+		}
+		release(movieName)
+		operators[resource.GroupVersionKind] = movieName
+		return nil
+	}
+	if err := register(mgr, resource, openAPISchema); err != nil {
+		return err
+	}
+	r := &ReconcileOperatorMovie{client: mgr.GetClient(), scheme: mgr.GetScheme(), gvk: resource.GroupVersionKind}
+	if err := addMovie(mgr, r); err != nil {
+		return err
+	}
+	controllers[resource.GroupVersionKind] = true
+	release(movieName)
+	operators[resource.GroupVersionKind] = movieName
+	return nil
+}
+
+// RemoveMovie releases kinds reconciled by the Movie, so that their resources
+// aren't reconciled anymore and another Movie can reconcile them. It's called
+// when the Movie is deleted or stops being an operator. CRDs are kept, since
+// deleting them would delete the resources.
+func RemoveMovie(movie types.NamespacedName) {
+	operatorsLock.Lock()
+	defer operatorsLock.Unlock()
+	release(movie)
+}
+
+func release(movie types.NamespacedName) {
+	for gvk, owner := range operators {
+		if owner == movie {
+			delete(operators, gvk)
+		}
+	}
+}
+
+// operatorMovie returns the Movie reconciling the kind
+func operatorMovie(gvk schema.GroupVersionKind) (types.NamespacedName, bool) {
+	operatorsLock.Lock()
+	defer operatorsLock.Unlock()
+	movie, ok := operators[gvk]
+	return movie, ok
+}
+
+// operatorSchema returns the OpenAPI schema of the custom resource. Status is
+// written by Kuberik, so it's always allowed to hold any fields.
+func operatorSchema(spec *corev1alpha1.MovieOperator) (*apiextensionv1beta1.JSONSchemaProps, error) {
+	if spec.Schema == nil || len(spec.Schema.Raw) == 0 {
+		return nil, nil
+	}
+	openAPISchema := &apiextensionv1beta1.JSONSchemaProps{}
+	if err := json.Unmarshal(spec.Schema.Raw, openAPISchema); err != nil {
+		return nil, fmt.Errorf("Invalid operator schema: %s", err)
+	}
+	if openAPISchema.Properties == nil {
+		openAPISchema.Properties = make(map[string]apiextensionv1beta1.JSONSchemaProps)
+	}
+	preserveUnknownFields := true
+	openAPISchema.Properties["status"] = apiextensionv1beta1.JSONSchemaProps{
+		Type:                   "object",
+		XPreserveUnknownFields: &preserveUnknownFields,
+	}
+	return openAPISchema, nil
+}
+
+func addMovie(mgr manager.Manager, r *ReconcileOperatorMovie) error {
+	c, err := controller.New(fmt.Sprintf("operator-movie-controller-%s", strings.ToLower(r.gvk.Kind)), mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Only changes of the spec start a new Play
+	err = c.Watch(&Kind{GroupVersionKind: r.gvk}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes of Plays to report their results
+	owner := &unstructured.Unstructured{}
+	owner.SetGroupVersionKind(r.gvk)
+	err = c.Watch(&source.Kind{Type: &corev1alpha1.Play{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    owner,
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileOperatorMovie implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileOperatorMovie{}
+
+// ReconcileOperatorMovie reconciles custom resources of an operator Movie
+type ReconcileOperatorMovie struct {
+	client client.Client
+	scheme *runtime.Scheme
+	gvk    schema.GroupVersionKind
+}
+
+// Reconcile plays the Movie for every generation of the custom resource and
+// writes the phase and frame outputs of the Play to the status of the resource.
+func (r *ReconcileOperatorMovie) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name, "Kind", r.gvk.Kind)
+	reqLogger.Info("Reconciling Operator Movie")
+
+	movie, ok := operatorMovie(r.gvk)
+	if !ok {
+		// Kind was released, so its resources are left alone
+		return reconcile.Result{}, nil
+	}
+	ctx := context.TODO()
+	instance := &unstructured.Unstructured{}
+	instance.SetGroupVersionKind(r.gvk)
+	if err := r.client.Get(ctx, request.NamespacedName, instance); err != nil {
+		if errors.IsNotFound(err) {
+			// Plays are owned by the resource, so they are garbage collected
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	status, _ := instance.Object["status"].(map[string]interface{})
+	if status == nil {
+		status = make(map[string]interface{})
+	}
+	observedGeneration, _, _ := unstructured.NestedInt64(instance.Object, "status", "observedGeneration")
+	playName, _ := status["play"].(string)
+
+	if playName == "" || observedGeneration != instance.GetGeneration() {
+		play, err := r.newPlay(movie, instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		// Play of the generation might already exist if recording it in the
+		// status of the resource failed
+		if err := r.client.Create(ctx, play); err != nil && !errors.IsAlreadyExists(err) {
+			return reconcile.Result{}, err
+		}
+		reqLogger.Info("Playing Movie", "Play", play.Name)
+		instance.Object["status"] = map[string]interface{}{
+			"observedGeneration": instance.GetGeneration(),
+			"play":               play.Name,
+			"phase":              string(corev1alpha1.PlayCreated),
+		}
+		return reconcile.Result{}, r.client.Status().Update(ctx, instance)
+	}
+
+	play := &corev1alpha1.Play{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: instance.GetNamespace(), Name: playName}, play)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if play.Status.Phase == "" || status["phase"] == string(play.Status.Phase) {
+		return reconcile.Result{}, nil
+	}
+
+	status["phase"] = string(play.Status.Phase)
+	switch play.Status.Phase {
+	case corev1alpha1.PlayComplete, corev1alpha1.PlayFailed, corev1alpha1.PlayError:
+		outputs, err := kuberikRuntime.FrameOutputs(r.client, play)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		status["outputs"] = decodeOutputs(outputs)
+	}
+	instance.Object["status"] = status
+	return reconcile.Result{}, r.client.Status().Update(ctx, instance)
+}
+
+func (r *ReconcileOperatorMovie) newPlay(movieName types.NamespacedName, instance *unstructured.Unstructured) (*corev1alpha1.Play, error) {
+	movie := &corev1alpha1.Movie{}
+	if err := r.client.Get(context.TODO(), movieName, movie); err != nil {
+		return nil, err
+	}
+	instanceJSON, err := instance.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	eventType := corev1alpha1.ResourceUpdated
+	if instance.GetGeneration() <= 1 {
+		eventType = corev1alpha1.ResourceCreated
+	}
+	event := screener.ResourceEvent{
+		Type:      eventType,
+		Name:      instance.GetName(),
+		Namespace: instance.GetNamespace(),
+	}
+
+	play := screener.NewPlay(movie, "", event.Vars())
+	play.GenerateName = ""
+	play.Name = generationPlayName(movie, instance)
+	// Plays need to be in the same namespace as the resource which owns them
+	play.Namespace = instance.GetNamespace()
+	play.Spec.Input = string(instanceJSON)
+	if err := controllerutil.SetControllerReference(instance, play, r.scheme); err != nil {
+		return nil, err
+	}
+	return play, nil
+}
+
+// generationPlayName returns the name of the Play of the current generation of
+// the resource. Names are derived from the UID of the resource, so that a
+// resource recreated under the same name doesn't reuse Plays of the old one.
+func generationPlayName(movie *corev1alpha1.Movie, instance *unstructured.Unstructured) string {
+	uid := sha256.Sum256([]byte(instance.GetUID()))
+	return fmt.Sprintf("%s-%x-%d", movie.Name, uid[:5], instance.GetGeneration())
+}
+
+// decodeOutputs parses outputs which are valid JSON, so that they are
+// structured in the status of the resource.
+func decodeOutputs(outputs map[string]string) map[string]interface{} {
+	decoded := make(map[string]interface{})
+	for name, output := range outputs {
+		var value interface{}
+		if err := json.Unmarshal([]byte(output), &value); err == nil {
+			decoded[name] = value
+		} else {
+			decoded[name] = output
+		}
+	}
+	return decoded
+}
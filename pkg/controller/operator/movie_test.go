@@ -0,0 +1,94 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileOperatorMovieCreatesPlayOncePerGeneration(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "extensions.kuberik.io", Version: "v1alpha1", Kind: "App"}
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	registerScheme(scheme, Resource{GroupVersionKind: gvk})
+
+	app := &unstructured.Unstructured{}
+	app.SetGroupVersionKind(gvk)
+	app.SetName("web")
+	app.SetNamespace("default")
+	app.SetUID("1234")
+	app.SetGeneration(2)
+	movie := &corev1alpha1.Movie{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	// Play of the generation was created, but recording it in the status of the resource failed
+	existing := &corev1alpha1.Play{ObjectMeta: metav1.ObjectMeta{Name: generationPlayName(movie, app), Namespace: "default"}}
+	c := fake.NewFakeClientWithScheme(scheme, app, movie, existing)
+
+	operatorsLock.Lock()
+	operators[gvk] = types.NamespacedName{Namespace: "default", Name: "app"}
+	operatorsLock.Unlock()
+	defer RemoveMovie(types.NamespacedName{Namespace: "default", Name: "app"})
+
+	r := &ReconcileOperatorMovie{client: c, scheme: scheme, gvk: gvk}
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	plays := &corev1alpha1.PlayList{}
+	if err := c.List(context.TODO(), plays); err != nil {
+		t.Fatal(err)
+	}
+	if len(plays.Items) != 1 {
+		t.Errorf("Expected a single Play of the generation, got %d", len(plays.Items))
+	}
+	updated := &unstructured.Unstructured{}
+	updated.SetGroupVersionKind(gvk)
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "web"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if play, _, _ := unstructured.NestedString(updated.Object, "status", "play"); play != existing.Name {
+		t.Errorf("Expected status to record Play %s, got %q", existing.Name, play)
+	}
+}
+
+func TestReconcileOperatorMovieReleased(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "extensions.kuberik.io", Version: "v1alpha1", Kind: "App"}
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	registerScheme(scheme, Resource{GroupVersionKind: gvk})
+
+	app := &unstructured.Unstructured{}
+	app.SetGroupVersionKind(gvk)
+	app.SetName("web")
+	app.SetNamespace("default")
+	movie := &corev1alpha1.Movie{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	c := fake.NewFakeClientWithScheme(scheme, app, movie)
+
+	operatorsLock.Lock()
+	operators[gvk] = types.NamespacedName{Namespace: "default", Name: "app"}
+	operatorsLock.Unlock()
+	RemoveMovie(types.NamespacedName{Namespace: "default", Name: "app"})
+
+	r := &ReconcileOperatorMovie{client: c, scheme: scheme, gvk: gvk}
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}); err != nil {
+		t.Fatal(err)
+	}
+	plays := &corev1alpha1.PlayList{}
+	if err := c.List(context.TODO(), plays); err != nil {
+		t.Fatal(err)
+	}
+	if len(plays.Items) != 0 {
+		t.Errorf("Expected resources of a released kind not to be played, got %d Plays", len(plays.Items))
+	}
+}
@@ -1,48 +1,98 @@
 package operator
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
 
 var log = logf.Log.WithName("controller_operator")
 
-/**
-* USER ACTION REQUIRED: This is a scaffold file intended for the user to modify with their own Controller
-* business logic.  Delete these comments after modifying this file.*
- */
-
-// Add creates a new Operator Controller and adds it to the Manager. The Manager will set fields on the Controller
-// and Start it when the Manager is Started.
-func Add(mgr manager.Manager, movie *corev1alpha1.MovieSpec, gvk schema.GroupVersionKind) error {
-	resource := newResourceFromGVK(gvk)
-	register(mgr, resource)
-	return add(mgr, newReconciler(mgr, movie, resource), resource)
+var (
+	watchedLock sync.Mutex
+	// watched holds event stores of kinds which already have a running controller.
+	// Controllers can't be stopped, so a single controller per kind is shared by
+	// all screeners and its store stops recording events once no screener
+	// watches the kind anymore.
+	watched = make(map[schema.GroupVersionKind]*eventStore)
+)
+
+// Add creates a new Operator Controller for resources of the given kind and adds it to the Manager.
+// Calling Add for a kind which already has a controller resumes recording its events.
+func Add(mgr manager.Manager, gvk schema.GroupVersionKind) error {
+	watchedLock.Lock()
+	defer watchedLock.Unlock()
+	if events, ok := watched[gvk]; ok {
+		events.setWatched(true)
+		return nil
+	}
+
+	if !mgr.GetScheme().Recognizes(gvk) {
+		registerScheme(mgr.GetScheme(), Resource{GroupVersionKind: gvk})
+	}
+	events := newEventStore()
+	if err := add(mgr, newReconciler(mgr, gvk, events), gvk, events); err != nil {
+		return err
+	}
+	watched[gvk] = events
+	return nil
+}
+
+// Prune stops recording events of kinds which aren't watched by any resource screener.
+func Prune(c client.Client) error {
+	screeners := &corev1alpha1.ScreenerList{}
+	if err := c.List(context.TODO(), screeners); err != nil {
+		return err
+	}
+	kinds := make(map[schema.GroupVersionKind]bool)
+	for _, s := range screeners.Items {
+		if s.Spec.Resource != nil && s.DeletionTimestamp == nil {
+			kinds[screener.ResourceGVK(s.Spec.Resource)] = true
+		}
+	}
+
+	watchedLock.Lock()
+	defer watchedLock.Unlock()
+	for gvk, events := range watched {
+		events.setWatched(kinds[gvk])
+	}
+	return nil
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager, movie *corev1alpha1.MovieSpec, resource Resource) reconcile.Reconciler {
-	return &ReconcileOperator{config: mgr.GetConfig(), movie: movie, scheme: mgr.GetScheme(), resource: resource}
+func newReconciler(mgr manager.Manager, gvk schema.GroupVersionKind, events *eventStore) reconcile.Reconciler {
+	return &ReconcileOperator{client: mgr.GetClient(), gvk: gvk, events: events}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler, resource Resource) error {
+func add(mgr manager.Manager, r reconcile.Reconciler, gvk schema.GroupVersionKind, events *eventStore) error {
 	// Create a new controller
-	c, err := controller.New("operator-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New(fmt.Sprintf("operator-controller-%s", strings.ToLower(gvk.Kind)), mgr, controller.Options{Reconciler: r})
 	if err != nil {
 		return err
 	}
 
-	err = c.Watch(&Kind{GroupVersionKind: resource.GroupVersionKind}, &handler.EnqueueRequestForObject{})
+	// Updates which don't change the generation (e.g. status updates) don't trigger Plays
+	err = c.Watch(
+		&Kind{GroupVersionKind: gvk},
+		&enqueueResourceEvent{events: events},
+		predicate.GenerationChangedPredicate{},
+	)
 	if err != nil {
 		return err
 	}
@@ -53,40 +103,129 @@ func add(mgr manager.Manager, r reconcile.Reconciler, resource Resource) error {
 // blank assignment to verify that ReconcileOperator implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileOperator{}
 
-// ReconcileOperator reconciles a Operator object
+// ReconcileOperator reconciles resources watched by screeners
 type ReconcileOperator struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	config   *rest.Config
-	scheme   *runtime.Scheme
-	resource Resource
-	movie    *corev1alpha1.MovieSpec
+	client client.Client
+	gvk    schema.GroupVersionKind
+	events *eventStore
 }
 
-// Reconcile reads that state of the cluster for a Operator object and makes changes based on the state read
-// and what is in the Operator.Spec
-// TODO(user): Modify this Reconcile function to implement your Controller logic.  This example creates
-// a Pod as an example
-// Note:
-// The Controller will requeue the Request to be processed again if the returned error is non-nil or
-// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+// Reconcile creates a Play for every recorded event of the resource and every Screener
+// which watches it. The JSON of the resource is passed as the input of the Play.
 func (r *ReconcileOperator) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling Operator")
 
-	client, _ := dynamic.NewForConfig(r.config)
-	object, _ := client.
-		Resource(schema.GroupVersionResource{
-			Group:    r.resource.Group,
-			Version:  r.resource.Version,
-			Resource: r.resource.plural(),
-		}).Namespace(request.Namespace).Get(request.Name, metav1.GetOptions{})
+	events := r.events.pop(request.NamespacedName)
+	if len(events) == 0 {
+		return reconcile.Result{}, nil
+	}
 
-	// objectJSON, err := object.MarshalJSON()
-	_, err := object.MarshalJSON()
-	if err != nil {
+	screeners := &corev1alpha1.ScreenerList{}
+	var opts []client.ListOption
+	if request.Namespace != "" {
+		opts = append(opts, client.InNamespace(request.Namespace))
+	}
+	if err := r.client.List(context.TODO(), screeners, opts...); err != nil {
+		r.events.retry(request.NamespacedName, events...)
 		return reconcile.Result{}, err
 	}
-	// return reconcile.Result{}, kuberikruntime.InitPlay(string(objectJSON), *r.movie.Screenplay, "reconcile")
-	return reconcile.Result{}, nil
+
+	// Only screeners which failed to trigger a Play for an event retry it, so
+	// that Plays of the other screeners aren't created twice
+	var failed []pendingEvent
+	var createErr error
+	for _, e := range events {
+		var retry []string
+		for i := range screeners.Items {
+			s := &screeners.Items[i]
+			if !e.includes(s.Name) || s.Spec.Resource == nil || screener.ResourceGVK(s.Spec.Resource) != r.gvk {
+				continue
+			}
+			if err := r.reconcileEvent(s, e); err != nil {
+				reqLogger.Error(err, "Failed to reconcile event", "Screener", s.Name, "Event", e.Type)
+				retry = append(retry, s.Name)
+				createErr = err
+			}
+		}
+		if len(retry) > 0 {
+			failed = append(failed, pendingEvent{ResourceEvent: e.ResourceEvent, screeners: retry})
+		}
+	}
+	if createErr != nil {
+		r.events.retry(request.NamespacedName, failed...)
+	}
+	return reconcile.Result{}, createErr
+}
+
+// reconcileEvent triggers a Play of the screener for the event and records the
+// generation of the resource in the status of the screener. Generations tell
+// apart resources listed when the operator starts: resources which weren't
+// changed since they were seen are skipped, changed resources are updated and
+// resources created before the screener are only recorded.
+func (r *ReconcileOperator) reconcileEvent(s *corev1alpha1.Screener, e pendingEvent) error {
+	var generations map[string]int64
+	if s.Status.Resource != nil {
+		generations = s.Status.Resource.Generations
+	}
+	last, seen := generations[string(e.uid)]
+	event := e.ResourceEvent
+	trigger := true
+	switch {
+	case e.Type == corev1alpha1.ResourceDeleted:
+	case seen && last >= e.generation:
+		return nil
+	case seen && e.Type == corev1alpha1.ResourceCreated:
+		event.Type = corev1alpha1.ResourceUpdated
+	case !seen && e.Type == corev1alpha1.ResourceCreated && e.created.Before(&s.CreationTimestamp):
+		trigger = false
+	}
+
+	if trigger && screener.MatchResourceEvent(s.Spec.Resource, r.gvk, event.Type) {
+		log.Info("Triggering Play", "Screener", s.Name, "Event", event.Type, "Namespace", e.Namespace, "Name", e.Name)
+		if err := r.createPlay(s, e, event); err != nil {
+			return err
+		}
+	}
+
+	if e.Type == corev1alpha1.ResourceDeleted {
+		if !seen {
+			return nil
+		}
+		delete(generations, string(e.uid))
+	} else {
+		if s.Status.Resource == nil {
+			s.Status.Resource = &corev1alpha1.ResourceScreenerStatus{}
+		}
+		if s.Status.Resource.Generations == nil {
+			s.Status.Resource.Generations = make(map[string]int64)
+		}
+		s.Status.Resource.Generations[string(e.uid)] = e.generation
+	}
+	return r.client.Status().Update(context.TODO(), s)
+}
+
+// createPlay creates the Play of the screener for the event. The Play is named
+// after the generation of the resource, so that a Play already created for it
+// by an event whose generation wasn't recorded isn't created again.
+func (r *ReconcileOperator) createPlay(s *corev1alpha1.Screener, e pendingEvent, event screener.ResourceEvent) error {
+	movie := &corev1alpha1.Movie{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: s.Namespace, Name: s.Spec.Movie}, movie)
+	if err != nil {
+		return err
+	}
+	play := screener.NewPlay(movie, s.Name, event.Vars())
+	play.Spec.Input = string(event.Object)
+	play.GenerateName = ""
+	revision := strconv.FormatInt(e.generation, 10)
+	if event.Type == corev1alpha1.ResourceDeleted {
+		revision = "deleted"
+	}
+	play.Name = kubeutils.Name(s.Name, e.Name, string(e.uid), revision)
+	if err := r.client.Create(context.TODO(), play); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
 }
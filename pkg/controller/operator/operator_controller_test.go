@@ -0,0 +1,171 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+// generateNameClient names created objects from their generate name like the API server
+type generateNameClient struct {
+	client.Client
+	generated int
+}
+
+func (c *generateNameClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if meta, ok := obj.(metav1.Object); ok && meta.GetName() == "" {
+		c.generated++
+		meta.SetName(fmt.Sprintf("%s%d", meta.GetGenerateName(), c.generated))
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func resourceScreener(name, movie string) *corev1alpha1.Screener {
+	return &corev1alpha1.Screener{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1alpha1.ScreenerSpec{
+			Movie:    movie,
+			Resource: &corev1alpha1.ResourceScreener{APIVersion: "v1", Kind: "ConfigMap"},
+		},
+	}
+}
+
+func TestReconcileRetriesFailedScreeners(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := &generateNameClient{Client: fake.NewFakeClientWithScheme(scheme,
+		resourceScreener("a", "deploy"),
+		// Movie of screener b doesn't exist yet, so its Play can't be created
+		resourceScreener("b", "test"),
+		&corev1alpha1.Movie{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "default"}},
+	)}
+	events := newEventStore()
+	r := &ReconcileOperator{client: c, gvk: configMapGVK, events: events}
+
+	name := types.NamespacedName{Namespace: "default", Name: "config"}
+	events.push(name, pendingEvent{ResourceEvent: screener.ResourceEvent{Type: corev1alpha1.ResourceCreated, Name: "config", Namespace: "default"}})
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: name}); err == nil {
+		t.Fatal("Expected reconcile to fail while Movie test is missing")
+	}
+	if err := c.Create(context.TODO(), &corev1alpha1.Movie{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: name}); err != nil {
+		t.Fatal(err)
+	}
+
+	plays := &corev1alpha1.PlayList{}
+	if err := c.List(context.TODO(), plays); err != nil {
+		t.Fatal(err)
+	}
+	triggered := make(map[string]int)
+	for _, play := range plays.Items {
+		triggered[play.Labels[screener.ScreenerLabel]]++
+	}
+	if len(plays.Items) != 2 || triggered["a"] != 1 || triggered["b"] != 1 {
+		t.Errorf("Expected a single Play of each screener, got %v", triggered)
+	}
+}
+
+func TestReconcileAfterRestart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-time.Hour)
+	s := resourceScreener("a", "deploy")
+	s.CreationTimestamp = metav1.NewTime(created)
+	s.Status.Resource = &corev1alpha1.ResourceScreenerStatus{Generations: map[string]int64{"changed": 1, "same": 1}}
+	c := fake.NewFakeClientWithScheme(scheme, s, &corev1alpha1.Movie{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "default"}})
+	events := newEventStore()
+	r := &ReconcileOperator{client: c, gvk: configMapGVK, events: events}
+
+	// Informer lists all objects as created when the operator starts
+	listed := []pendingEvent{
+		{uid: "old", generation: 1, created: metav1.NewTime(created.Add(-time.Hour))},
+		{uid: "new", generation: 1, created: metav1.NewTime(created.Add(time.Minute))},
+		{uid: "changed", generation: 2, created: metav1.NewTime(created.Add(-time.Hour))},
+		{uid: "same", generation: 1, created: metav1.NewTime(created.Add(time.Minute))},
+	}
+	for restart := 0; restart < 2; restart++ {
+		for _, e := range listed {
+			e.ResourceEvent = screener.ResourceEvent{Type: corev1alpha1.ResourceCreated, Name: string(e.uid), Namespace: "default"}
+			name := types.NamespacedName{Namespace: "default", Name: e.Name}
+			events.push(name, e)
+			if _, err := r.Reconcile(reconcile.Request{NamespacedName: name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	plays := &corev1alpha1.PlayList{}
+	if err := c.List(context.TODO(), plays); err != nil {
+		t.Fatal(err)
+	}
+	triggered := make(map[string]string)
+	for _, play := range plays.Items {
+		name, _ := play.Spec.Vars.Get(screener.ResourceNameVar)
+		triggered[name], _ = play.Spec.Vars.Get(screener.ResourceEventVar)
+	}
+	expected := map[string]string{"new": string(corev1alpha1.ResourceCreated), "changed": string(corev1alpha1.ResourceUpdated)}
+	if fmt.Sprint(triggered) != fmt.Sprint(expected) {
+		t.Errorf("Expected Plays of events %v, got %v", expected, triggered)
+	}
+	updated := &corev1alpha1.Screener{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "a"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	generations := map[string]int64{"old": 1, "new": 1, "changed": 2, "same": 1}
+	if fmt.Sprint(updated.Status.Resource.Generations) != fmt.Sprint(generations) {
+		t.Errorf("Expected generations %v, got %v", generations, updated.Status.Resource.Generations)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secretGVK := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	configMaps, secrets := newEventStore(), newEventStore()
+	watchedLock.Lock()
+	watched[configMapGVK], watched[secretGVK] = configMaps, secrets
+	watchedLock.Unlock()
+	defer func() {
+		watchedLock.Lock()
+		delete(watched, configMapGVK)
+		delete(watched, secretGVK)
+		watchedLock.Unlock()
+	}()
+
+	name := types.NamespacedName{Namespace: "default", Name: "secret"}
+	secrets.push(name, pendingEvent{})
+	if err := Prune(fake.NewFakeClientWithScheme(scheme, resourceScreener("a", "deploy"))); err != nil {
+		t.Fatal(err)
+	}
+	if !configMaps.watched {
+		t.Error("Expected ConfigMaps to stay watched")
+	}
+	if secrets.watched || len(secrets.pop(name)) != 0 {
+		t.Error("Expected events of Secrets to be dropped")
+	}
+	secrets.push(name, pendingEvent{})
+	if len(secrets.pop(name)) != 0 {
+		t.Error("Expected events of Secrets not to be recorded")
+	}
+}
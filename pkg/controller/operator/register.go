@@ -1,6 +1,8 @@
 package operator
 
 import (
+	"time"
+
 	apiextensionv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apiextension "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -8,22 +10,29 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/scheme"
 )
 
-func register(mgr manager.Manager, resource Resource) {
-	registerCRD(mgr.GetConfig(), resource)
+const crdEstablishTimeout = 30 * time.Second
+
+func register(mgr manager.Manager, resource Resource, schema *apiextensionv1beta1.JSONSchemaProps) error {
+	if err := registerCRD(mgr.GetConfig(), resource, schema); err != nil {
+		return err
+	}
 	registerScheme(mgr.GetScheme(), resource)
+	return nil
 }
 
-func registerCRD(config *rest.Config, resource Resource) {
+// registerCRD creates or updates the CRD of the resource and waits until it's established
+func registerCRD(config *rest.Config, resource Resource, schema *apiextensionv1beta1.JSONSchemaProps) error {
 	client, err := apiextension.NewForConfig(config)
 	if err != nil {
-		// TODO
+		return err
 	}
-	_, err = client.ApiextensionsV1beta1().CustomResourceDefinitions().Create(&apiextensionv1beta1.CustomResourceDefinition{
+	crd := &apiextensionv1beta1.CustomResourceDefinition{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: resource.crdName(),
 		},
@@ -37,11 +46,43 @@ func registerCRD(config *rest.Config, resource Resource) {
 				Plural:   resource.plural(),
 				Singular: resource.singular(),
 			},
+			Subresources: &apiextensionv1beta1.CustomResourceSubresources{
+				Status: &apiextensionv1beta1.CustomResourceSubresourceStatus{},
+			},
 		},
-	})
-	if err != nil && apierrors.IsAlreadyExists(err) {
-		log.Error(err, "Failed to create CRD")
 	}
+	if schema != nil {
+		crd.Spec.Validation = &apiextensionv1beta1.CustomResourceValidation{OpenAPIV3Schema: schema}
+	}
+
+	crds := client.ApiextensionsV1beta1().CustomResourceDefinitions()
+	_, err = crds.Create(crd)
+	if apierrors.IsAlreadyExists(err) {
+		var existing *apiextensionv1beta1.CustomResourceDefinition
+		existing, err = crds.Get(crd.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		existing.Spec.Validation = crd.Spec.Validation
+		existing.Spec.Subresources = crd.Spec.Subresources
+		_, err = crds.Update(existing)
+	}
+	if err != nil {
+		return err
+	}
+
+	return wait.PollImmediate(time.Second, crdEstablishTimeout, func() (bool, error) {
+		current, err := crds.Get(crd.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == apiextensionv1beta1.Established && condition.Status == apiextensionv1beta1.ConditionTrue {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
 }
 
 func registerScheme(runtimeScheme *runtime.Scheme, resource Resource) {
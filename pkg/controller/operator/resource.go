@@ -20,7 +20,7 @@ func (r Resource) listKind() string {
 }
 
 func (r Resource) crdName() string {
-	return fmt.Sprintf("%s.%s", r.plural(), extensionsGroup)
+	return fmt.Sprintf("%s.%s", r.plural(), r.Group)
 }
 
 func (r Resource) singular() string {
@@ -32,7 +32,9 @@ func (r Resource) plural() string {
 }
 
 func newResourceFromGVK(gvk schema.GroupVersionKind) Resource {
-	gvk.Group = extensionsGroup
+	if gvk.Group == "" {
+		gvk.Group = extensionsGroup
+	}
 	return Resource{
 		GroupVersionKind: gvk,
 	}
@@ -0,0 +1,182 @@
+package play
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/metrics"
+	"github.com/kuberik/kuberik/pkg/randutils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// time a replica waits before claiming a Play for each Play it already holds,
+	// so Plays are claimed by the least loaded replicas
+	claimDelayPerPlay = 200 * time.Millisecond
+)
+
+// leases tracks leases of Plays held by a replica. Leases are stored in the
+// status of Plays and updated with optimistic concurrency, so only one replica
+// holds a Play at a time. Number of held leases is reported as running Plays.
+type leases struct {
+	lock sync.Mutex
+	// runner is the ID of the replica
+	runner string
+	held   map[types.NamespacedName]bool
+	// time when an unclaimed Play was first seen
+	claimable map[types.NamespacedName]time.Time
+}
+
+func newLeases() *leases {
+	return &leases{
+		held:      make(map[types.NamespacedName]bool),
+		claimable: make(map[types.NamespacedName]time.Time),
+	}
+}
+
+// id returns the ID of the replica. It's read lazily, since the engine
+// configuration is initialized after controllers are added to the Manager.
+func (l *leases) id() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.runner == "" {
+		l.runner = config.RunnerID
+		if l.runner == "" {
+			l.runner = randutils.Rand()
+		}
+	}
+	return l.runner
+}
+
+func (l *leases) hold(key types.NamespacedName) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.held[key] = true
+	delete(l.claimable, key)
+	metrics.PlaysRunning.Set(float64(len(l.held)))
+}
+
+// release drops the lease and returns whether it was held
+func (l *leases) release(key types.NamespacedName) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	held := l.held[key]
+	delete(l.held, key)
+	delete(l.claimable, key)
+	metrics.PlaysRunning.Set(float64(len(l.held)))
+	return held
+}
+
+func (l *leases) count() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.held)
+}
+
+// claimDelay returns the time left before the replica can claim the Play
+func (l *leases) claimDelay(key types.NamespacedName, now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delay := time.Duration(len(l.held)) * claimDelayPerPlay
+	if delay > config.LeaseDuration/2 {
+		delay = config.LeaseDuration / 2
+	}
+	first, ok := l.claimable[key]
+	if !ok {
+		first = now
+		l.claimable[key] = now
+	}
+	if elapsed := now.Sub(first); elapsed < delay {
+		return delay - elapsed
+	}
+	return 0
+}
+
+// lease makes sure the replica holds the lease of the running Play. If the Play
+// is held by another replica or the replica waits before claiming it, it
+// returns false with the time after which the Play should be reconciled again.
+func (r *ReconcilePlay) lease(ctx context.Context, instance *corev1alpha1.Play) (bool, time.Duration, error) {
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	now := time.Now()
+	renewPeriod := config.LeaseDuration / 3
+
+	if instance.Status.Runner == r.leases.id() {
+		if instance.Status.RenewTime == nil || now.Sub(instance.Status.RenewTime.Time) >= renewPeriod {
+			if err := r.renew(ctx, instance, now); err != nil {
+				return false, 0, err
+			}
+		}
+		r.leases.hold(key)
+		return true, renewPeriod, nil
+	}
+
+	if r.leases.release(key) {
+		// Play was taken over, executions are recovered by the new runner
+		log.Info(fmt.Sprintf("Lost lease of %s/%s to %s", instance.Namespace, instance.Name, instance.Status.Runner))
+		r.player.Forget(key)
+	}
+	if wait := r.claimWait(instance, now); wait > 0 {
+		return false, wait, nil
+	}
+
+	if instance.Status.Runner != "" {
+		log.Info(fmt.Sprintf("Recovering %s/%s from %s...", instance.Namespace, instance.Name, instance.Status.Runner))
+	}
+	// Update fails with a conflict if another replica claimed the Play first
+	if err := r.renew(ctx, instance, now); err != nil {
+		return false, 0, err
+	}
+	r.leases.hold(key)
+	return true, renewPeriod, nil
+}
+
+// claimWait returns the time the replica needs to wait before it can claim the
+// Play, or zero if it can claim it now. Plays held by other replicas can be
+// claimed once their lease expires, and replicas holding more Plays wait
+// longer, so Plays are claimed by the least loaded replicas.
+func (r *ReconcilePlay) claimWait(instance *corev1alpha1.Play, now time.Time) time.Duration {
+	if instance.Status.Runner != "" && instance.Status.RenewTime != nil {
+		if expires := instance.Status.RenewTime.Add(config.LeaseDuration); now.Before(expires) {
+			return expires.Sub(now)
+		}
+	}
+	if config.MaxPlays > 0 && r.leases.count() >= config.MaxPlays {
+		return config.LeaseDuration / 3
+	}
+	return r.leases.claimDelay(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}, now)
+}
+
+func (r *ReconcilePlay) renew(ctx context.Context, instance *corev1alpha1.Play, now time.Time) error {
+	instance.Status.Runner = r.leases.id()
+	instance.Status.RenewTime = &metav1.Time{Time: now}
+	return r.client.Status().Update(ctx, instance)
+}
+
+// unelectedManager adds runnables which run on every replica instead of only
+// on the leader, since Plays are sharded across replicas with leases
+type unelectedManager struct {
+	manager.Manager
+}
+
+func (m unelectedManager) Add(runnable manager.Runnable) error {
+	// Fields are set on the runnable, since they can't be set through the wrapper
+	if err := m.Manager.SetFields(runnable); err != nil {
+		return err
+	}
+	return m.Manager.Add(unelectedRunnable{runnable})
+}
+
+type unelectedRunnable struct {
+	manager.Runnable
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (unelectedRunnable) NeedLeaderElection() bool {
+	return false
+}
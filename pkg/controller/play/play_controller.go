@@ -3,18 +3,27 @@ package play
 import (
 	"context"
 	"fmt"
+	"time"
 
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
-	"github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/metrics"
 	kuberikRuntime "github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler"
+	"github.com/kuberik/kuberik/pkg/eventbus"
 	"github.com/kuberik/kuberik/pkg/randutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"github.com/tidwall/gjson"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -32,16 +41,57 @@ var log = logf.Log.WithName("controller_play")
 // Add creates a new Play Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	// Plays are sharded across replicas with leases, so the controller runs on every replica
+	mgr = unelectedManager{mgr}
+
+	// Plays stop being played when the Manager stops, so they can be recovered by another runner
+	ctx, cancel := context.WithCancel(context.Background())
+	err := mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		<-stop
+		cancel()
+		return nil
+	}))
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	r := newReconciler(ctx, mgr)
+	// Plays are reconciled when their executions finish
+	finished := make(chan event.GenericEvent)
+	r.player.Notify = func(key types.NamespacedName) {
+		play := &corev1alpha1.Play{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		select {
+		case finished <- event.GenericEvent{Meta: play, Object: play}:
+		case <-ctx.Done():
+		}
+	}
+	return add(mgr, r, finished)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcilePlay{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(ctx context.Context, mgr manager.Manager) *ReconcilePlay {
+	return NewReconcilePlay(ctx, mgr.GetClient(), mgr.GetScheme(), mgr.GetEventRecorderFor("play-controller"), scheduler.DefaultRegistry)
 }
 
-// add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+// NewReconcilePlay returns a ReconcilePlay which executes Plays with Schedulers of the registry
+// and emits Events of Plays with the recorder. Plays are played until the context is done.
+func NewReconcilePlay(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, schedulers *scheduler.Registry) *ReconcilePlay {
+	player := kuberikRuntime.NewPlayer(schedulers)
+	player.Recorder = recorder
+	return &ReconcilePlay{
+		ctx:      ctx,
+		client:   c,
+		scheme:   scheme,
+		recorder: recorder,
+		player:   player,
+		leases:   newLeases(),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler. Plays
+// are also reconciled on events received from the channel.
+func add(mgr manager.Manager, r reconcile.Reconciler, events <-chan event.GenericEvent) error {
 	// Create a new controller
 	c, err := controller.New("play-controller", mgr, controller.Options{Reconciler: r})
 	if err != nil {
@@ -54,9 +104,8 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// TODO(user): Modify this to be the types you create that are owned by the primary resource
-	// Watch for changes to secondary resource Pods and requeue the owner Play
-	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestForOwner{
+	// Watch for changes to Jobs of executions and requeue the owner Play
+	err = c.Watch(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &corev1alpha1.Play{},
 	})
@@ -64,6 +113,11 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	err = c.Watch(&source.Channel{Source: events}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -74,8 +128,15 @@ var _ reconcile.Reconciler = &ReconcilePlay{}
 type ReconcilePlay struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	scheme *runtime.Scheme
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	// player plays frames of running Plays
+	player *kuberikRuntime.Player
+	// leases of Plays held by the replica
+	leases *leases
+	// ctx is the context in which Plays are played
+	ctx context.Context
 }
 
 // Reconcile reads that state of the cluster for a Play object and makes changes based on the state read
@@ -96,47 +157,76 @@ func (r *ReconcilePlay) Reconcile(request reconcile.Request) (reconcile.Result,
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
-			// Return and don't requeue
+			// Owned objects are automatically garbage collected, Plays deleted
+			// while running are dropped by the replica holding them.
+			if r.leases.release(request.NamespacedName) {
+				r.player.Forget(request.NamespacedName)
+			}
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
+	// Plays cancelled before they started don't run at all
+	if instance.Spec.Cancel && (instance.Status.Phase == "" || instance.Status.Phase == corev1alpha1.PlayCreated) {
+		instance.Status.Phase = corev1alpha1.PlayFailed
+		r.recorder.Event(instance, corev1.EventTypeWarning, kuberikRuntime.ReasonPlayCancelled, "Play was cancelled")
+		r.phaseEvent(instance)
+		if err := r.client.Status().Update(ctx, instance); err != nil {
+			return reconcile.Result{Requeue: true}, err
+		}
+		r.recordFinished(instance)
+		return reconcile.Result{}, nil
+	}
+
 	switch instance.Status.Phase {
 	case "":
 		err := func() error {
-			err := provisionVarsConfigMap(instance)
+			err := r.provisionVarsConfigMap(instance)
 			if err != nil {
 				return err
 			}
-			err = provisionVolumes(instance)
+			err = r.provisionVolumes(instance)
 			return err
 		}()
 
 		if err != nil {
+			r.recorder.Eventf(instance, corev1.EventTypeWarning, kuberikRuntime.ReasonProvisioningFailed, "Failed to provision Play: %s", err)
+			setCondition(instance, corev1alpha1.PlayConditionProvisioned, corev1.ConditionFalse, kuberikRuntime.ReasonProvisioningFailed, err.Error())
 			instance.Status.Phase = corev1alpha1.PlayError
+			r.phaseEvent(instance)
 			if errUpdate := r.client.Status().Update(ctx, instance); errUpdate != nil {
 				return reconcile.Result{Requeue: true}, err
 			}
+			r.recordFinished(instance)
 			return reconcile.Result{}, err
 		}
 
+		setCondition(instance, corev1alpha1.PlayConditionProvisioned, corev1.ConditionTrue, "Provisioned", "")
 		instance.Status.Phase = corev1alpha1.PlayCreated
-		instance.Status.Runner = config.RunnerID
 		err = r.client.Status().Update(ctx, instance)
 		if err != nil {
 			return reconcile.Result{Requeue: true}, err
 		}
 
 	case corev1alpha1.PlayCreated:
+		// Replica starting the Play holds its lease, so new Plays are claimed
+		// the same way as Plays which are taken over
+		now := metav1.Now()
+		if wait := r.claimWait(instance, now.Time); wait > 0 {
+			return reconcile.Result{RequeueAfter: wait}, nil
+		}
 		instance.Status.Phase = corev1alpha1.PlayRunning
-		instance.Status.Runner = config.RunnerID
-		err := r.client.Status().Update(ctx, instance)
+		instance.Status.StartTime = &now
+		setCondition(instance, corev1alpha1.PlayConditionRunning, corev1.ConditionTrue, kuberikRuntime.ReasonPlayStarted, "")
+		err := r.renew(ctx, instance, now.Time)
 		if err != nil {
 			return reconcile.Result{Requeue: true}, err
 		}
+		r.leases.hold(request.NamespacedName)
+		r.phaseEvent(instance)
+		metrics.PlaysStarted.WithLabelValues(screener.MovieName(instance)).Inc()
 
 		varsConfigMap := corev1.ConfigMap{}
 		err = r.client.Get(context.TODO(), types.NamespacedName{Name: instance.Status.VarsConfigMap, Namespace: instance.Namespace}, &varsConfigMap)
@@ -155,24 +245,57 @@ func (r *ReconcilePlay) Reconcile(request reconcile.Request) (reconcile.Result,
 
 		log.Info(fmt.Sprintf("Running play %s", instance.Name))
 		populateRandomIDs(&instance.Spec)
+		// Trace context of the Play is persisted with IDs of frames
+		r.player.StartTrace(instance)
 		err = r.client.Update(context.TODO(), instance)
 		if err != nil {
 			return reconcile.Result{Requeue: true}, err
 		}
-		// TODO r.client.Get(ctx, request.NamespacedName, instance)
-		kuberikRuntime.Play(*instance)
 	case corev1alpha1.PlayRunning:
-		if instance.Status.Runner != config.RunnerID {
-			log.Info(fmt.Sprintf("Recovering %s/%s...", instance.Namespace, instance.Name))
-			instance.Status.Runner = config.RunnerID
-			err := r.client.Status().Update(ctx, instance)
-			if err != nil {
-				return reconcile.Result{Requeue: true}, err
+		held, requeueAfter, err := r.lease(ctx, instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !held {
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+		// Spec of the Play is updated with IDs of frames after it started
+		if instance.Status.ObservedGeneration != instance.Generation {
+			instance.Status.ObservedGeneration = instance.Generation
+			if err := r.client.Status().Update(ctx, instance); err != nil {
+				return reconcile.Result{}, err
 			}
-			// TODO r.client.Get(ctx, request.NamespacedName, instance)
-			kuberikRuntime.Play(*instance)
+		}
+		if instance.Spec.Cancel {
+			if err := r.player.Cancel(r.ctx, instance); err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{}, nil
+		}
+		// Next frames are played on every reconciliation, so executions
+		// finished while the Play wasn't held are picked up
+		finished, err := r.player.Reconcile(r.ctx, instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !finished {
+			// Lease is renewed periodically while the Play is running
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
 		}
 	case corev1alpha1.PlayComplete, corev1alpha1.PlayFailed, corev1alpha1.PlayError:
+		r.leases.release(request.NamespacedName)
+		// Event is published only once, so that it isn't published again
+		// after it expired and was deleted
+		if published := instance.Status.Condition(corev1alpha1.PlayConditionPublished); published == nil || published.Status != corev1.ConditionTrue {
+			event, err := eventbus.NewPlayFinishedEvent(instance)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			if err := eventbus.Publish(r.client, event); err != nil {
+				return reconcile.Result{}, err
+			}
+			setCondition(instance, corev1alpha1.PlayConditionPublished, corev1.ConditionTrue, "Published", fmt.Sprintf("Published Event %s", event.Name))
+		}
 		for _, pvcName := range instance.Status.ProvisionedVolumes {
 			r.client.Delete(context.TODO(), &corev1.PersistentVolumeClaim{
 				ObjectMeta: metav1.ObjectMeta{
@@ -182,6 +305,7 @@ func (r *ReconcilePlay) Reconcile(request reconcile.Request) (reconcile.Result,
 			})
 		}
 		instance.Status.ProvisionedVolumes = make(map[string]string)
+		r.complete(instance)
 		err = r.client.Status().Update(context.TODO(), instance)
 		if err != nil {
 			return reconcile.Result{Requeue: true}, err
@@ -190,6 +314,60 @@ func (r *ReconcilePlay) Reconcile(request reconcile.Request) (reconcile.Result,
 	return reconcile.Result{}, nil
 }
 
+// complete records completion of a Play which reached a final phase
+func (r *ReconcilePlay) complete(instance *corev1alpha1.Play) {
+	// Plays cancelled after they finished aren't marked as cancelled
+	cancelled := instance.Status.CompletionTime == nil || instance.Status.Condition(corev1alpha1.PlayConditionCancelled) != nil
+	cancelled = cancelled && instance.Spec.Cancel
+	if instance.Status.CompletionTime == nil {
+		now := metav1.Now()
+		instance.Status.CompletionTime = &now
+		metrics.PlaysFinished.WithLabelValues(screener.MovieName(instance), string(instance.Status.Phase)).Inc()
+	}
+	_, reason, _ := kuberikRuntime.PhaseEvent(instance.Status.Phase)
+	message := fmt.Sprintf("Play entered phase %s", instance.Status.Phase)
+	succeeded := corev1.ConditionFalse
+	if instance.Status.Phase == corev1alpha1.PlayComplete {
+		succeeded = corev1.ConditionTrue
+	}
+	if cancelled {
+		reason, message = kuberikRuntime.ReasonPlayCancelled, "Play was cancelled"
+		setCondition(instance, corev1alpha1.PlayConditionCancelled, corev1.ConditionTrue, reason, message)
+	}
+	setCondition(instance, corev1alpha1.PlayConditionRunning, corev1.ConditionFalse, reason, message)
+	setCondition(instance, corev1alpha1.PlayConditionSucceeded, succeeded, reason, message)
+}
+
+// setCondition sets the condition of the Play observed at its current generation
+func setCondition(instance *corev1alpha1.Play, conditionType corev1alpha1.PlayConditionType, status corev1.ConditionStatus, reason, message string) {
+	instance.Status.ObservedGeneration = instance.Generation
+	instance.Status.SetCondition(corev1alpha1.PlayCondition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// phaseEvent emits the Event of the phase the Play entered
+func (r *ReconcilePlay) phaseEvent(instance *corev1alpha1.Play) {
+	if eventType, reason, ok := kuberikRuntime.PhaseEvent(instance.Status.Phase); ok {
+		r.recorder.Eventf(instance, eventType, reason, "Play entered phase %s", instance.Status.Phase)
+	}
+}
+
+// recordFinished records a Play which finished before it was played in the
+// history, since history of played Plays is recorded by the Player
+func (r *ReconcilePlay) recordFinished(instance *corev1alpha1.Play) {
+	if r.player.History == nil {
+		return
+	}
+	if err := r.player.History.RecordPlayFinished(instance, instance.Status.Phase, time.Now()); err != nil {
+		log.Error(err, fmt.Sprintf("Failed to record history of %s", instance.Name))
+	}
+}
+
 func populateRandomIDs(playSpec *corev1alpha1.PlaySpec) {
 	var frames []*corev1alpha1.Frame
 	for k := range playSpec.Screenplays {
@@ -206,21 +384,29 @@ func populateRandomIDs(playSpec *corev1alpha1.PlaySpec) {
 	}
 }
 
-func provisionVarsConfigMap(instance *corev1alpha1.Play) error {
+func (r *ReconcilePlay) provisionVarsConfigMap(instance *corev1alpha1.Play) error {
 	varsConfigMapName := fmt.Sprintf("%s-vars", instance.Name)
 	configMapValues := make(map[string]string)
 	for _, v := range instance.Spec.Vars {
-		configMapValues[v.Name] = v.Value
+		value := v.Value
+		if v.ValueFrom != nil && v.ValueFrom.InputRef != nil {
+			value = gjson.Get(instance.Spec.Input, v.ValueFrom.InputRef.GJSONPath).String()
+		}
+		configMapValues[v.Name] = value
 	}
 	varsConfigMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      varsConfigMapName,
 			Namespace: instance.Namespace,
+			Labels:    kuberikRuntime.PlayLabels(instance),
 		},
 		Data: configMapValues,
 	}
+	if err := controllerutil.SetControllerReference(instance, varsConfigMap, r.scheme); err != nil {
+		return err
+	}
 
-	err := config.Client.Create(context.TODO(), varsConfigMap)
+	err := r.client.Create(context.TODO(), varsConfigMap)
 	if err != nil && !errors.IsAlreadyExists(err) {
 		return err
 	}
@@ -229,7 +415,7 @@ func provisionVarsConfigMap(instance *corev1alpha1.Play) error {
 }
 
 // ProvisionVolumes provisions volumes for the duration of the play
-func provisionVolumes(play *corev1alpha1.Play) (err error) {
+func (r *ReconcilePlay) provisionVolumes(play *corev1alpha1.Play) (err error) {
 	if play.Status.ProvisionedVolumes == nil {
 		play.Status.ProvisionedVolumes = make(map[string]string)
 	}
@@ -237,18 +423,20 @@ func provisionVolumes(play *corev1alpha1.Play) (err error) {
 	for _, volumeClaimTemplate := range play.Spec.VolumeClaimTemplates {
 		pvcName := fmt.Sprintf("%s-%s", play.Name, volumeClaimTemplate.Name)
 
-		err = config.Client.Create(context.TODO(), &corev1.PersistentVolumeClaim{
+		pvc := &corev1.PersistentVolumeClaim{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      pvcName,
 				Namespace: play.Namespace,
-				Labels: map[string]string{
-					"provisionedBy":        "kuberik",
-					"core.kuberik.io/play": play.Name,
-				},
+				Labels:    kuberikRuntime.PlayLabels(play),
 			},
 			Spec: volumeClaimTemplate.Spec,
-		})
+		}
+		pvc.Labels["provisionedBy"] = "kuberik"
+		if err = controllerutil.SetControllerReference(play, pvc, r.scheme); err != nil {
+			return
+		}
 
+		err = r.client.Create(context.TODO(), pvc)
 		if err != nil && !errors.IsAlreadyExists(err) {
 			return
 		}
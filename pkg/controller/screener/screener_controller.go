@@ -0,0 +1,186 @@
+package screener
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/controller/operator"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_screener")
+
+const defaultPollInterval = time.Minute
+
+// Add creates a new Screener Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileScreener{
+		mgr:      mgr,
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		cacheDir: filepath.Join(os.TempDir(), "kuberik", "screeners"),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("screener-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource Screener. Status updates are
+	// ignored since polling is driven by requeues.
+	err = c.Watch(&source.Kind{Type: &corev1alpha1.Screener{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileScreener implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileScreener{}
+
+// ReconcileScreener reconciles a Screener object
+type ReconcileScreener struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	mgr    manager.Manager
+	scheme *runtime.Scheme
+	// cacheDir is the directory where screeners keep local state, e.g. git repositories
+	cacheDir string
+}
+
+// Reconcile reads that state of the cluster for a Screener object and creates Plays
+// from the referenced Movie when the screener observes a change.
+func (r *ReconcileScreener) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling Screener")
+
+	// Fetch the Screener instance
+	instance := &corev1alpha1.Screener{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			os.RemoveAll(r.screenerDir(request.NamespacedName))
+			return reconcile.Result{}, operator.Prune(r.client)
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	// The screener might have stopped watching a kind of resources
+	if err := operator.Prune(r.client); err != nil {
+		return reconcile.Result{}, err
+	}
+	switch {
+	case instance.Spec.Git != nil:
+		return r.reconcileGit(instance)
+	case instance.Spec.Resource != nil:
+		// Plays are created by the operator controller watching the resource
+		return reconcile.Result{}, operator.Add(r.mgr, screener.ResourceGVK(instance.Spec.Resource))
+	}
+	return reconcile.Result{}, nil
+}
+
+func (r *ReconcileScreener) reconcileGit(instance *corev1alpha1.Screener) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	interval := defaultPollInterval
+	if instance.Spec.Git.PollInterval != nil {
+		interval = instance.Spec.Git.PollInterval.Duration
+	}
+
+	poller := &screener.GitPoller{Dir: r.screenerDir(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})}
+	revisions, err := poller.Poll(instance.Spec.Git)
+	if err != nil {
+		reqLogger.Error(err, "Failed to poll git repository")
+		return reconcile.Result{RequeueAfter: interval}, nil
+	}
+
+	seen := screener.RevisionMap(revisions)
+	// Revisions seen on the first poll are only recorded so that creating a
+	// screener doesn't trigger a Play for every existing ref.
+	var createErr error
+	if instance.Status.Git != nil {
+		previous := instance.Status.Git.Revisions
+		for _, revision := range screener.ChangedRevisions(previous, revisions) {
+			reqLogger.Info("Triggering Play", "Ref", revision.Ref, "Commit", revision.Commit)
+			if err := r.createPlay(instance, revision); err != nil {
+				createErr = err
+				// Keep the previous revision so that the Play is triggered on the next poll
+				if commit, ok := previous[revision.Ref]; ok {
+					seen[revision.Ref] = commit
+				} else {
+					delete(seen, revision.Ref)
+				}
+			}
+		}
+	}
+
+	now := metav1.Now()
+	instance.Status.Git = &corev1alpha1.GitScreenerStatus{
+		Revisions:    seen,
+		LastPollTime: &now,
+	}
+	if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+		return reconcile.Result{}, err
+	}
+	if createErr != nil {
+		return reconcile.Result{}, createErr
+	}
+	return reconcile.Result{RequeueAfter: interval}, nil
+}
+
+// createPlay creates the Play of a revision. The Play is named after the
+// revision, so that a Play already created for it on a poll whose status
+// update failed isn't created again.
+func (r *ReconcileScreener) createPlay(instance *corev1alpha1.Screener, revision screener.GitRevision) error {
+	movie := &corev1alpha1.Movie{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Spec.Movie}, movie)
+	if err != nil {
+		return err
+	}
+	play := screener.NewPlay(movie, instance.Name, revision.Vars())
+	play.GenerateName = ""
+	play.Name = kubeutils.Name(instance.Name, revision.Ref, shortCommit(revision.Commit))
+	if err := r.client.Create(context.TODO(), play); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func (r *ReconcileScreener) screenerDir(name types.NamespacedName) string {
+	return filepath.Join(r.cacheDir, name.Namespace, name.Name)
+}
@@ -0,0 +1,127 @@
+package screener
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Dave", "-c", "user.email=dave@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// failingStatusClient fails the next status updates as if they conflicted
+type failingStatusClient struct {
+	client.Client
+	failures int
+}
+
+func (c *failingStatusClient) Status() client.StatusWriter {
+	return &failingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type failingStatusWriter struct {
+	client.StatusWriter
+	client *failingStatusClient
+}
+
+func (w *failingStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if w.client.failures > 0 {
+		w.client.failures--
+		return fmt.Errorf("Conflict")
+	}
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func TestReconcileGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "kuberik-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	remote := filepath.Join(dir, "remote.git")
+	work := filepath.Join(dir, "work")
+	os.Mkdir(remote, 0755)
+	os.Mkdir(work, 0755)
+	git(t, remote, "init", "--bare")
+	git(t, work, "init")
+	git(t, work, "checkout", "-b", "master")
+	git(t, work, "commit", "--allow-empty", "-m", "first")
+	git(t, work, "push", remote, "master")
+
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := &failingStatusClient{Client: fake.NewFakeClientWithScheme(scheme,
+		&corev1alpha1.Screener{
+			ObjectMeta: metav1.ObjectMeta{Name: "master", Namespace: "default"},
+			Spec: corev1alpha1.ScreenerSpec{
+				Movie: "app",
+				Git:   &corev1alpha1.GitScreener{Repository: remote, Branches: []string{"master"}},
+			},
+		},
+		&corev1alpha1.Movie{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}},
+	)}
+	r := &ReconcileScreener{client: c, cacheDir: filepath.Join(dir, "cache")}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "master"}}
+	plays := func() []corev1alpha1.Play {
+		list := &corev1alpha1.PlayList{}
+		if err := c.List(context.TODO(), list); err != nil {
+			t.Fatal(err)
+		}
+		return list.Items
+	}
+
+	// Existing revisions are only recorded
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatal(err)
+	}
+	if len(plays()) != 0 {
+		t.Errorf("Expected existing revisions not to trigger Plays, got %d", len(plays()))
+	}
+
+	git(t, work, "commit", "--allow-empty", "-m", "second")
+	git(t, work, "push", remote, "master")
+	// Play is created, but the new revision isn't recorded
+	c.failures = 1
+	if _, err := r.Reconcile(request); err == nil {
+		t.Fatal("Expected reconcile to fail when status can't be updated")
+	}
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatal(err)
+	}
+
+	triggered := plays()
+	if len(triggered) != 1 {
+		t.Fatalf("Expected a single Play of the new revision, got %d", len(triggered))
+	}
+	commit, _ := triggered[0].Spec.Vars.Get(screener.GitCommitVar)
+	if commit != git(t, work, "rev-parse", "master") || triggered[0].Labels[screener.ScreenerLabel] != "master" {
+		t.Errorf("Expected Play of the new revision, got %+v", triggered[0])
+	}
+}
@@ -2,28 +2,114 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/kuberik/kuberik/pkg/engine/history"
 	"github.com/kuberik/kuberik/pkg/randutils"
 
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var Config *rest.Config
 var Client client.Client
 
+// Recorder emits Events of the engine. Events aren't emitted if it's nil.
+var Recorder record.EventRecorder
+
+// History records history of Plays. History isn't recorded if it's nil.
+var History *history.Store
+
+// HistoryDialect is the gorm dialect of the history database, sqlite3 by default.
+// It's set with the KUBERIK_HISTORY_DIALECT environment variable.
+var HistoryDialect = history.DefaultDialect
+
+// HistoryDSN is the data source of the history database, such as a path of the
+// SQLite database. History isn't recorded if it's empty.
+// It's set with the KUBERIK_HISTORY_DSN environment variable.
+var HistoryDSN string
+
+// RunnerID identifies the operator replica. It's the name of the operator Pod
+// if it's set with the POD_NAME environment variable, and random otherwise.
 var RunnerID string
 var Host string
 
+// LeaseDuration is the time after which Plays of a replica which stopped
+// renewing its leases are taken over by other replicas.
+var LeaseDuration = 30 * time.Second
+
+// MaxPlays is the maximum number of Plays a replica plays at once, unlimited if 0.
+// It's set with the KUBERIK_MAX_PLAYS environment variable.
+var MaxPlays int
+
+// StuckGracePeriod is the time after which executions whose Pods can't be
+// scheduled or can't pull their images are failed instead of waiting forever.
+// It's set with the KUBERIK_STUCK_GRACE_PERIOD environment variable.
+var StuckGracePeriod = 5 * time.Minute
+
+// LogArchiveLines is the number of last lines of output of each container of
+// finished executions which are archived, so logs outlive Pods. Logs aren't
+// archived if it's 0. It's set with the KUBERIK_LOG_ARCHIVE_LINES environment variable.
+var LogArchiveLines = 1000
+
+// Scheduler is the backend executing Plays: kubernetes (default), container or shell.
+// It's set with the KUBERIK_SCHEDULER environment variable.
+var Scheduler string
+
+// ContainerHost is the address of the container API used by the container scheduler.
+// It's set with the KUBERIK_CONTAINER_HOST environment variable.
+var ContainerHost string
+
+// WorkDir is the directory of executions of the local schedulers.
+// It's set with the KUBERIK_WORK_DIR environment variable.
+var WorkDir string
+
+// APIAddress is the address the REST API is served on, port 8080 of Host by
+// default. The API isn't served if it's empty. It's set with the
+// KUBERIK_API_ADDRESS environment variable.
+var APIAddress string
+
+// APIAllowedOrigins are origins from which browsers can call the REST API.
+// It's set with the comma-separated KUBERIK_API_ALLOWED_ORIGINS environment variable.
+var APIAllowedOrigins []string
+
+// OTLPEndpoint is the OTLP/HTTP endpoint traces of Plays are exported to, such
+// as http://otel-collector:4318. Plays aren't traced if it's empty.
+// It's set with the KUBERIK_OTLP_ENDPOINT environment variable.
+var OTLPEndpoint string
+
 func InitConfig(c *rest.Config) {
 	Config = c
-	RunnerID = randutils.Rand()
+	RunnerID = os.Getenv("POD_NAME")
+	if RunnerID == "" {
+		RunnerID = randutils.Rand()
+	}
 }
 
 func InitClient(c client.Client) {
 	Client = c
 }
 
+func InitRecorder(r record.EventRecorder) {
+	Recorder = r
+}
+
+// InitHistory opens the history database if its data source is configured
+func InitHistory() error {
+	if HistoryDSN == "" {
+		return nil
+	}
+	store, err := history.Open(HistoryDialect, HistoryDSN)
+	if err != nil {
+		return err
+	}
+	History = store
+	return nil
+}
+
 func init() {
 	if _, ok := os.LookupEnv("KUBERNETES_SERVICE_HOST"); ok {
 		// Running in the cluster - listen on all interfaces
@@ -32,4 +118,27 @@ func init() {
 		// Running on development machine - use localhost to avoid MacOS firewall prompts
 		Host = "127.0.0.1"
 	}
+
+	Scheduler = os.Getenv("KUBERIK_SCHEDULER")
+	ContainerHost = os.Getenv("KUBERIK_CONTAINER_HOST")
+	WorkDir = os.Getenv("KUBERIK_WORK_DIR")
+	OTLPEndpoint = os.Getenv("KUBERIK_OTLP_ENDPOINT")
+	APIAddress = Host + ":8080"
+	if address, ok := os.LookupEnv("KUBERIK_API_ADDRESS"); ok {
+		APIAddress = address
+	}
+	if origins := os.Getenv("KUBERIK_API_ALLOWED_ORIGINS"); origins != "" {
+		APIAllowedOrigins = strings.Split(origins, ",")
+	}
+	if dialect := os.Getenv("KUBERIK_HISTORY_DIALECT"); dialect != "" {
+		HistoryDialect = dialect
+	}
+	HistoryDSN = os.Getenv("KUBERIK_HISTORY_DSN")
+	MaxPlays, _ = strconv.Atoi(os.Getenv("KUBERIK_MAX_PLAYS"))
+	if gracePeriod, err := time.ParseDuration(os.Getenv("KUBERIK_STUCK_GRACE_PERIOD")); err == nil {
+		StuckGracePeriod = gracePeriod
+	}
+	if lines, err := strconv.Atoi(os.Getenv("KUBERIK_LOG_ARCHIVE_LINES")); err == nil {
+		LogArchiveLines = lines
+	}
 }
@@ -0,0 +1,193 @@
+// Package enginetest runs Plays in-process through the Play controller with a
+// fake Scheduler and fake clients, so the engine can be tested end to end
+// without a cluster.
+package enginetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kuberik/kuberik/pkg/apis"
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/controller/play"
+	"github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/fake"
+	versionedfake "github.com/kuberik/kuberik/pkg/generated/clientset/versioned/fake"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// DefaultTimeout is the time in which a Play needs to finish
+	DefaultTimeout = 10 * time.Second
+	// Runner is the ID of the operator replica playing Plays of the tests
+	Runner       = "enginetest"
+	pollInterval = 10 * time.Millisecond
+	// number of Events kept by the recorder until they're read
+	recordedEvents = 1000
+)
+
+// Harness runs Plays through ReconcilePlay with a fake Scheduler
+type Harness struct {
+	t *testing.T
+
+	// Client is the fake client holding all objects of the test
+	Client client.Client
+	Scheme *runtime.Scheme
+	// Scheduler is the default Scheduler of the Plays
+	Scheduler *fake.Scheduler
+	// Schedulers is the registry used by the reconciler. Other Schedulers can be added to it.
+	Schedulers *scheduler.Registry
+	Reconciler *play.ReconcilePlay
+	// Recorder records Events emitted by the reconciler
+	Recorder *record.FakeRecorder
+	// Timeout is the time in which a Play needs to finish
+	Timeout time.Duration
+}
+
+// New creates a Harness with the objects
+func New(t *testing.T, objs ...runtime.Object) *Harness {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	// Reconcilers of all tests act as the same operator replica
+	if config.RunnerID == "" {
+		config.RunnerID = Runner
+	}
+	c := fakeclient.NewFakeClientWithScheme(s, objs...)
+	fakeScheduler := fake.NewScheduler(c)
+	schedulers := scheduler.NewRegistry("fake")
+	schedulers.Add("fake", fakeScheduler)
+	recorder := record.NewFakeRecorder(recordedEvents)
+
+	return &Harness{
+		t:          t,
+		Client:     c,
+		Scheme:     s,
+		Scheduler:  fakeScheduler,
+		Schedulers: schedulers,
+		Reconciler: play.NewReconcilePlay(context.Background(), c, s, recorder, schedulers),
+		Recorder:   recorder,
+		Timeout:    DefaultTimeout,
+	}
+}
+
+// RunPlay creates the Play and reconciles it until it finishes. It returns the
+// Play after the controller has processed its final phase.
+func (h *Harness) RunPlay(instance *corev1alpha1.Play) *corev1alpha1.Play {
+	h.t.Helper()
+	if err := h.Client.Create(context.TODO(), instance); err != nil {
+		h.t.Fatalf("Failed to create Play: %s", err)
+	}
+
+	return h.Wait(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})
+}
+
+// Wait reconciles the Play until it finishes. It returns the Play after the
+// controller has processed its final phase.
+func (h *Harness) Wait(key types.NamespacedName) *corev1alpha1.Play {
+	h.t.Helper()
+	deadline := time.Now().Add(h.Timeout)
+	for time.Now().Before(deadline) {
+		h.Reconcile(key)
+		current := h.Play(key)
+		switch current.Status.Phase {
+		case corev1alpha1.PlayComplete, corev1alpha1.PlayFailed, corev1alpha1.PlayError:
+			// Let the controller clean up after the Play
+			h.Reconcile(key)
+			return h.Play(key)
+		case corev1alpha1.PlayRunning:
+			time.Sleep(pollInterval)
+		}
+	}
+	h.t.Fatalf("Play %s didn't finish in %s, phases: %v", key.Name, h.Timeout, h.Scheduler.Phases(key.Namespace, key.Name))
+	return nil
+}
+
+// Restart replaces the reconciler with a new one, as if the operator replica
+// was restarted. Executions started by the Schedulers keep running.
+func (h *Harness) Restart() {
+	h.Reconciler = play.NewReconcilePlay(context.Background(), h.Client, h.Scheme, h.Recorder, h.Schedulers)
+}
+
+// Events returns Events recorded since the last call, formatted as "<type> <reason> <message>"
+func (h *Harness) Events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-h.Recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// Reconcile runs a single reconciliation of the Play
+func (h *Harness) Reconcile(key types.NamespacedName) reconcile.Result {
+	h.t.Helper()
+	result, err := h.Reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+	if err != nil {
+		h.t.Fatalf("Failed to reconcile Play %s: %s", key.Name, err)
+	}
+	return result
+}
+
+// Play returns the current state of the Play
+func (h *Harness) Play(key types.NamespacedName) *corev1alpha1.Play {
+	h.t.Helper()
+	instance := &corev1alpha1.Play{}
+	if err := h.Client.Get(context.TODO(), key, instance); err != nil {
+		h.t.Fatalf("Failed to get Play %s: %s", key.Name, err)
+	}
+	return instance
+}
+
+// Clientset returns a generated fake clientset with a snapshot of Kuberik
+// objects, for testing code built on the generated clientset such as the CLI.
+func (h *Harness) Clientset() *versionedfake.Clientset {
+	h.t.Helper()
+	clientset := versionedfake.NewSimpleClientset()
+	add := func(resource string, obj runtime.Object, namespace string) {
+		// Objects are created with explicit resources, since the tracker guesses
+		// wrong resource name of Plays
+		gvr := corev1alpha1.SchemeGroupVersion.WithResource(resource)
+		if err := clientset.Tracker().Create(gvr, obj, namespace); err != nil {
+			h.t.Fatalf("Failed to add %s to the clientset: %s", resource, err)
+		}
+	}
+
+	plays := &corev1alpha1.PlayList{}
+	movies := &corev1alpha1.MovieList{}
+	screeners := &corev1alpha1.ScreenerList{}
+	events := &corev1alpha1.EventList{}
+	for _, list := range []runtime.Object{plays, movies, screeners, events} {
+		if err := h.Client.List(context.TODO(), list); err != nil {
+			h.t.Fatalf("Failed to list objects: %s", err)
+		}
+	}
+	for i := range plays.Items {
+		add("plays", &plays.Items[i], plays.Items[i].Namespace)
+	}
+	for i := range movies.Items {
+		add("movies", &movies.Items[i], movies.Items[i].Namespace)
+	}
+	for i := range screeners.Items {
+		add("screeners", &screeners.Items[i], screeners.Items[i].Namespace)
+	}
+	for i := range events.Items {
+		add("events", &events.Items[i], events.Items[i].Namespace)
+	}
+	return clientset
+}
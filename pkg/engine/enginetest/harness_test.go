@@ -0,0 +1,635 @@
+package enginetest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/history"
+	"github.com/kuberik/kuberik/pkg/engine/metrics"
+	"github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/fake"
+	"github.com/kuberik/kuberik/pkg/engine/tracing"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func frame(name, image string) corev1alpha1.Frame {
+	return corev1alpha1.Frame{
+		Name: name,
+		Action: &corev1alpha1.Exec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Image: image}},
+				},
+			},
+		},
+	}
+}
+
+func newPlay(name string, scenes ...corev1alpha1.Scene) *corev1alpha1.Play {
+	return &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1alpha1.PlaySpec{
+			Vars: corev1alpha1.Vars{{Name: "FOO", Value: "bar"}},
+			Screenplays: []corev1alpha1.Screenplay{{
+				Name:   "main",
+				Scenes: scenes,
+			}},
+		},
+	}
+}
+
+func TestPlayComplete(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("slow", fake.Script{Delay: 50 * time.Millisecond, Output: "done\n"})
+
+	instance := newPlay("complete",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "slow"), frame("b", "fast")}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("c", "fast")}},
+	)
+	instance.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "workspace"}},
+	}
+	result := h.RunPlay(instance)
+
+	if result.Status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Expected Play to complete, got %s", result.Status.Phase)
+	}
+	if len(result.Status.Frames) != 3 {
+		t.Errorf("Expected results of 3 frames, got %v", result.Status.Frames)
+	}
+	for id, exit := range result.Status.Frames {
+		if exit != 0 {
+			t.Errorf("Frame %s failed with %d", id, exit)
+		}
+	}
+	if runs := h.Scheduler.Runs(); len(runs) != 3 {
+		t.Errorf("Expected 3 executions, got %d", len(runs))
+	}
+
+	// Vars are provisioned in a ConfigMap mounted to every frame
+	varsConfigMap := &corev1.ConfigMap{}
+	err := h.Client.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: result.Status.VarsConfigMap}, varsConfigMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if varsConfigMap.Data["FOO"] != "bar" {
+		t.Errorf("Vars ConfigMap has wrong data: %v", varsConfigMap.Data)
+	}
+	if !metav1.IsControlledBy(varsConfigMap, result) {
+		t.Errorf("Vars ConfigMap is not controlled by the Play: %v", varsConfigMap.OwnerReferences)
+	}
+	for _, run := range h.Scheduler.Runs() {
+		if len(run.Exec.Template.Spec.Containers[0].EnvFrom) == 0 {
+			t.Errorf("Vars are not populated in execution %s", run.Name)
+		}
+		if run.Labels[runtime.PlayLabel] != "complete" || run.Labels[runtime.FrameLabel] == "" || run.Labels[runtime.SceneLabel] == "" {
+			t.Errorf("Execution %s is not labeled with its Play: %v", run.Name, run.Labels)
+		}
+	}
+
+	// Provisioned volumes are released when the Play finishes
+	if len(result.Status.ProvisionedVolumes) != 0 {
+		t.Errorf("Provisioned volumes not released: %v", result.Status.ProvisionedVolumes)
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := h.Client.List(context.TODO(), pvcs); err != nil {
+		t.Fatal(err)
+	}
+	if len(pvcs.Items) != 0 {
+		t.Errorf("Expected provisioned PVCs to be deleted, found %d", len(pvcs.Items))
+	}
+
+	event := &corev1alpha1.Event{}
+	if err := h.Client.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: kubeutils.Name("complete", "finished")}, event); err != nil {
+		t.Errorf("Play finished Event not published: %s", err)
+	}
+
+	plays, err := h.Clientset().CoreV1alpha1().Plays("default").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plays.Items) != 1 {
+		t.Errorf("Expected 1 Play in the clientset, got %d", len(plays.Items))
+	}
+}
+
+func TestPlayFailed(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 1})
+
+	result := h.RunPlay(newPlay("failed",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "broken"), frame("b", "fine")}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("c", "fine")}},
+	))
+
+	if result.Status.Phase != corev1alpha1.PlayFailed {
+		t.Errorf("Expected Play to fail, got %s", result.Status.Phase)
+	}
+	// Scene after the failed one is not played
+	if runs := h.Scheduler.Runs(); len(runs) != 2 {
+		t.Errorf("Expected 2 executions, got %d", len(runs))
+	}
+}
+
+func TestFrameFailures(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 2})
+	h.Scheduler.Script("hungry", fake.Script{Exit: 137, Reason: execution.ReasonOOMKilled, Message: "Container ran out of memory"})
+
+	result := h.RunPlay(newPlay("failures",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "broken"), frame("b", "hungry"), frame("c", "fine")}},
+	))
+
+	expected := map[string]corev1alpha1.FrameFailure{
+		"a": {Reason: execution.ReasonError, Message: "Exited with code 2"},
+		"b": {Reason: execution.ReasonOOMKilled, Message: "Container ran out of memory"},
+	}
+	for _, f := range result.Spec.Screenplays[0].Scenes[0].Frames {
+		failure, failed := result.Status.FrameFailures[f.ID]
+		if want, ok := expected[f.Name]; failed != ok || failure != want {
+			t.Errorf("Expected failure of frame %s to be %v, got %v", f.Name, want, failure)
+		}
+	}
+}
+
+func TestPlayEvents(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 1})
+
+	h.RunPlay(newPlay("events",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "fine")}},
+		corev1alpha1.Scene{Name: "test", Frames: []corev1alpha1.Frame{frame("b", "broken")}},
+	))
+
+	var reasons []string
+	for _, event := range h.Events() {
+		reasons = append(reasons, strings.Fields(event)[1])
+	}
+	expected := []string{
+		runtime.ReasonPlayStarted,
+		runtime.ReasonSceneStarted, runtime.ReasonFrameStarted, runtime.ReasonFrameFinished, runtime.ReasonSceneFinished,
+		runtime.ReasonSceneStarted, runtime.ReasonFrameStarted, runtime.ReasonFrameFailed, runtime.ReasonSceneFailed,
+		runtime.ReasonPlayFailed,
+	}
+	if !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("Expected Events %v, got %v", expected, reasons)
+	}
+}
+
+func TestFrameRetried(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("flaky", fake.Script{Retries: 2, Delay: 300 * time.Millisecond})
+
+	result := h.RunPlay(newPlay("retried", corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "flaky")}}))
+	if result.Status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Expected Play to complete, got %s", result.Status.Phase)
+	}
+
+	retried := 0
+	for _, event := range h.Events() {
+		if strings.Contains(event, runtime.ReasonFrameRetried) {
+			retried++
+		}
+	}
+	if retried != 2 {
+		t.Errorf("Expected 2 FrameRetried Events, got %d", retried)
+	}
+	if runs := h.Scheduler.Runs(); len(runs) != 1 {
+		t.Errorf("Expected retries to run in the same execution, got %d executions", len(runs))
+	}
+}
+
+func TestPlayConditions(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 1})
+
+	for _, test := range []struct {
+		image     string
+		succeeded corev1.ConditionStatus
+		reason    string
+	}{
+		{"fine", corev1.ConditionTrue, runtime.ReasonPlayCompleted},
+		{"broken", corev1.ConditionFalse, runtime.ReasonPlayFailed},
+	} {
+		result := h.RunPlay(newPlay("conditions-"+test.image,
+			corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", test.image)}},
+		))
+
+		expected := map[corev1alpha1.PlayConditionType]corev1.ConditionStatus{
+			corev1alpha1.PlayConditionProvisioned: corev1.ConditionTrue,
+			corev1alpha1.PlayConditionRunning:     corev1.ConditionFalse,
+			corev1alpha1.PlayConditionSucceeded:   test.succeeded,
+		}
+		for conditionType, status := range expected {
+			condition := result.Status.Condition(conditionType)
+			if condition == nil || condition.Status != status || condition.LastTransitionTime.IsZero() {
+				t.Errorf("Expected %s condition of %s to be %s, got %+v", conditionType, result.Name, status, condition)
+			}
+		}
+		if reason := result.Status.Condition(corev1alpha1.PlayConditionSucceeded).Reason; reason != test.reason {
+			t.Errorf("Expected Succeeded condition of %s with reason %s, got %s", result.Name, test.reason, reason)
+		}
+		if result.Status.StartTime == nil || result.Status.CompletionTime == nil || result.Status.CompletionTime.Before(result.Status.StartTime) {
+			t.Errorf("Expected start and completion time of %s, got %v and %v", result.Name, result.Status.StartTime, result.Status.CompletionTime)
+		}
+		if result.Status.ObservedGeneration != result.Generation {
+			t.Errorf("Expected observed generation %d of %s, got %d", result.Generation, result.Name, result.Status.ObservedGeneration)
+		}
+	}
+}
+
+func TestPlayMetrics(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 1, Retries: 1, Delay: 100 * time.Millisecond})
+	started := testutil.ToFloat64(metrics.PlaysStarted.WithLabelValues("metrics"))
+	failed := testutil.ToFloat64(metrics.PlaysFinished.WithLabelValues("metrics", string(corev1alpha1.PlayFailed)))
+	retries := testutil.ToFloat64(metrics.FrameRetries.WithLabelValues("metrics-frame"))
+
+	instance := newPlay("metrics", corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("metrics-frame", "broken")}})
+	instance.Labels = map[string]string{screener.MovieLabel: "metrics"}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	h.RunPlay(instance)
+	// Finished Plays are counted once
+	h.Reconcile(key)
+
+	if value := testutil.ToFloat64(metrics.PlaysStarted.WithLabelValues("metrics")); value != started+1 {
+		t.Errorf("Expected 1 started Play, got %v", value-started)
+	}
+	if value := testutil.ToFloat64(metrics.PlaysFinished.WithLabelValues("metrics", string(corev1alpha1.PlayFailed))); value != failed+1 {
+		t.Errorf("Expected 1 failed Play, got %v", value-failed)
+	}
+	if value := testutil.ToFloat64(metrics.PlaysRunning); value != 0 {
+		t.Errorf("Expected no running Plays, got %v", value)
+	}
+	if value := testutil.ToFloat64(metrics.FrameRetries.WithLabelValues("metrics-frame")); value != retries+1 {
+		t.Errorf("Expected 1 retried frame, got %v", value-retries)
+	}
+}
+
+func TestPlayTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	h := New(t)
+
+	const parent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	instance := newPlay("traced",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "fine")}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("b", "fine")}},
+	)
+	instance.Annotations = map[string]string{tracing.TraceparentAnnotation: parent}
+	result := h.RunPlay(instance)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.SpanContext().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+			t.Errorf("Span %s isn't part of the trace of the annotation", span.Name())
+		}
+		spans[span.SpanContext().SpanID().String()] = span
+	}
+	if len(spans) != 5 {
+		t.Fatalf("Expected spans of the Play, 2 scenes and 2 frames, got %d", len(spans))
+	}
+	parentName := func(span sdktrace.ReadOnlySpan) string {
+		if p, ok := spans[span.Parent().SpanID().String()]; ok {
+			return p.Name()
+		}
+		return span.Parent().SpanID().String()
+	}
+	for _, span := range spans {
+		expected := map[string]string{"Play": "b7ad6b7169203331", "Scene": "Play", "Frame": "Scene"}[span.Name()]
+		if parentName(span) != expected {
+			t.Errorf("Expected parent of %s to be %s, got %s", span.Name(), expected, parentName(span))
+		}
+	}
+
+	for _, run := range h.Scheduler.Runs() {
+		env := run.Exec.Template.Spec.Containers[0].Env
+		if len(env) == 0 || env[len(env)-1].Name != tracing.TraceparentEnv {
+			t.Fatalf("Expected %s in environment of %s, got %v", tracing.TraceparentEnv, run.Name, env)
+		}
+		spanID := strings.Split(env[len(env)-1].Value, "-")[2]
+		if span, ok := spans[spanID]; !ok || span.Name() != "Frame" {
+			t.Errorf("Expected %s of %s to reference a frame span", tracing.TraceparentEnv, run.Name)
+		}
+	}
+
+	// Trace context of the Play span is persisted for replicas which recover the Play
+	spanID := strings.Split(result.Annotations[tracing.TraceparentAnnotation], "-")[2]
+	if span, ok := spans[spanID]; !ok || span.Name() != "Play" {
+		t.Errorf("Expected annotation %s to reference the Play span, got %s", tracing.TraceparentAnnotation, result.Annotations[tracing.TraceparentAnnotation])
+	}
+}
+
+func TestPlayTraceRecovered(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	h := New(t)
+	h.Scheduler.Script("slow", fake.Script{Delay: 100 * time.Millisecond})
+
+	instance := newPlay("trace-recovered",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "slow")}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("b", "fine")}},
+	)
+	if err := h.Client.Create(context.TODO(), instance); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	for len(h.Scheduler.Runs()) < 1 {
+		h.Reconcile(key)
+	}
+	traceparent := h.Play(key).Annotations[tracing.TraceparentAnnotation]
+	if traceparent == "" {
+		t.Fatalf("Expected trace context of the Play in annotation %s", tracing.TraceparentAnnotation)
+	}
+
+	// Operator restarts while the first scene is running
+	h.Restart()
+	h.Wait(key)
+
+	playSpanID := strings.Split(traceparent, "-")[2]
+	scenes := 0
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.Name() != "Scene" {
+			continue
+		}
+		scenes++
+		if span.Parent().SpanID().String() != playSpanID {
+			t.Errorf("Expected recovered scene span to continue the Play span %s, got parent %s", playSpanID, span.Parent().SpanID())
+		}
+	}
+	if scenes != 2 {
+		t.Errorf("Expected 2 scene spans, got %d", scenes)
+	}
+}
+
+func TestPlayHistory(t *testing.T) {
+	store, err := history.Open(history.DefaultDialect, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	config.History = store
+	defer func() { config.History = nil }()
+	h := New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 1})
+
+	instance := newPlay("history",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "fine"), frame("b", "broken")}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("c", "fine")}},
+	)
+	h.RunPlay(instance)
+	// History survives pruning of the Play
+	if err := h.Client.Delete(context.TODO(), h.Play(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := history.Play{}
+	if err := store.DB().Preload("Scenes").Preload("Frames").Where("name = ?", "history").First(&recorded).Error; err != nil {
+		t.Fatal(err)
+	}
+	if recorded.Phase != string(corev1alpha1.PlayFailed) || recorded.FinishedAt == nil {
+		t.Errorf("Expected Play to be recorded as finished and failed, got %+v", recorded)
+	}
+	if len(recorded.Scenes) != 1 || !recorded.Scenes[0].Failed || recorded.Scenes[0].FinishedAt == nil {
+		t.Errorf("Expected a finished failed scene, got %+v", recorded.Scenes)
+	}
+	results := make(map[string]int)
+	for _, f := range recorded.Frames {
+		if f.ExitCode != nil {
+			results[f.Name] = *f.ExitCode
+		}
+	}
+	if expected := map[string]int{"a": 0, "b": 1}; !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected frame results %v, got %v", expected, results)
+	}
+
+	// Plays cancelled before they started are recorded as well
+	cancelled := newPlay("history-cancelled", corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "fine")}})
+	cancelled.Spec.Cancel = true
+	h.RunPlay(cancelled)
+	recorded = history.Play{}
+	if err := store.DB().Where("name = ?", "history-cancelled").First(&recorded).Error; err != nil {
+		t.Fatalf("Cancelled Play wasn't recorded: %s", err)
+	}
+	if recorded.Phase != string(corev1alpha1.PlayFailed) || recorded.FinishedAt == nil {
+		t.Errorf("Expected cancelled Play to be recorded as finished and failed, got %+v", recorded)
+	}
+}
+
+func TestPlayCancel(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("slow", fake.Script{Delay: time.Hour})
+
+	instance := newPlay("cancelled",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "slow")}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("b", "fine")}},
+	)
+	if err := h.Client.Create(context.TODO(), instance); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	for deadline := time.Now().Add(h.Timeout); len(h.Scheduler.Runs()) < 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("Frame wasn't started")
+		}
+		h.Reconcile(key)
+	}
+	// Play is cancelled by a replica which didn't start its executions
+	h.Restart()
+	running := h.Play(key)
+	running.Spec.Cancel = true
+	if err := h.Client.Update(context.TODO(), running); err != nil {
+		t.Fatal(err)
+	}
+	result := h.Wait(key)
+
+	if result.Status.Phase != corev1alpha1.PlayFailed {
+		t.Errorf("Expected cancelled Play to fail, got %s", result.Status.Phase)
+	}
+	if condition := result.Status.Condition(corev1alpha1.PlayConditionCancelled); condition == nil || condition.Status != corev1.ConditionTrue {
+		t.Errorf("Expected Cancelled condition, got %v", condition)
+	}
+	if runs := h.Scheduler.Runs(); len(runs) != 1 {
+		t.Errorf("Expected frames of the next scene not to run, got %d executions", len(runs))
+	}
+	if results := h.Scheduler.FrameResults(key.Namespace, key.Name); len(results) != 0 {
+		t.Errorf("Expected no frame results, got %v", results)
+	}
+	observations, _ := h.Scheduler.Observe(context.TODO(), key.Namespace, runtime.PlayLabels(result))
+	for name, observation := range observations {
+		if !observation.Finished || observation.ExitCode != fake.CancelledExitCode {
+			t.Errorf("Expected execution %s to be cancelled, got %+v", name, observation)
+		}
+	}
+}
+
+func TestPlayIgnoreErrors(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("broken", fake.Script{Exit: 1})
+
+	ignored := frame("a", "broken")
+	ignored.IgnoreErrors = true
+	result := h.RunPlay(newPlay("ignored",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{ignored}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("b", "fine")}},
+	))
+
+	if result.Status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Expected Play to complete, got %s", result.Status.Phase)
+	}
+	if runs := h.Scheduler.Runs(); len(runs) != 2 {
+		t.Errorf("Expected 2 executions, got %d", len(runs))
+	}
+}
+
+func TestPlayRecovered(t *testing.T) {
+	h := New(t)
+	h.Scheduler.Script("slow", fake.Script{Delay: 100 * time.Millisecond})
+
+	instance := newPlay("recovered",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "slow"), frame("b", "fast")}},
+		corev1alpha1.Scene{Name: "deploy", Frames: []corev1alpha1.Frame{frame("c", "fast")}},
+	)
+	if err := h.Client.Create(context.TODO(), instance); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	for deadline := time.Now().Add(h.Timeout); len(h.Scheduler.Runs()) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("Frames of the first scene weren't started")
+		}
+		h.Reconcile(key)
+	}
+
+	// Operator restarts while the first scene is running
+	h.Restart()
+	result := h.Wait(key)
+
+	started := 0
+	for _, event := range h.Events() {
+		if strings.Contains(event, runtime.ReasonFrameStarted) {
+			started++
+		}
+	}
+	if started != 3 {
+		t.Errorf("Expected recovered frames not to be reported as started again, got %d FrameStarted Events", started)
+	}
+
+	if result.Status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Expected Play to complete, got %s", result.Status.Phase)
+	}
+	if runs := h.Scheduler.Runs(); len(runs) != 3 {
+		t.Errorf("Expected 3 executions, got %d", len(runs))
+	}
+}
+
+func TestPlayLease(t *testing.T) {
+	now := time.Now()
+	held := newPlay("held", corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "image")}})
+	held.Status = corev1alpha1.PlayStatus{
+		Phase:     corev1alpha1.PlayRunning,
+		Runner:    "other",
+		RenewTime: &metav1.Time{Time: now},
+	}
+	expired := newPlay("expired", corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "image")}})
+	expired.Status = corev1alpha1.PlayStatus{
+		Phase:     corev1alpha1.PlayRunning,
+		Runner:    "crashed",
+		RenewTime: &metav1.Time{Time: now.Add(-time.Hour)},
+	}
+	h := New(t, held, expired)
+
+	// Play held by another replica isn't played until its lease expires
+	result := h.Reconcile(types.NamespacedName{Namespace: "default", Name: "held"})
+	if result.RequeueAfter == 0 {
+		t.Errorf("Expected Play held by another replica to be requeued")
+	}
+	if runs := h.Scheduler.Runs(); len(runs) != 0 {
+		t.Errorf("Play held by another replica was played: %v", runs)
+	}
+
+	// Play with an expired lease is taken over
+	taken := h.Wait(types.NamespacedName{Namespace: "default", Name: "expired"})
+	if taken.Status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Expected Play to complete, got %s", taken.Status.Phase)
+	}
+	if taken.Status.Runner != Runner {
+		t.Errorf("Expected Play to be taken over, runner is %s", taken.Status.Runner)
+	}
+}
+
+func TestPlayMaxPlays(t *testing.T) {
+	defer func(maxPlays int) { config.MaxPlays = maxPlays }(config.MaxPlays)
+	config.MaxPlays = 1
+	h := New(t)
+	h.Scheduler.Script("slow", fake.Script{Delay: time.Second})
+
+	first := newPlay("first", corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "slow")}})
+	second := newPlay("second", corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "image")}})
+	for _, instance := range []*corev1alpha1.Play{first, second} {
+		if err := h.Client.Create(context.TODO(), instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	firstKey := types.NamespacedName{Namespace: "default", Name: "first"}
+	secondKey := types.NamespacedName{Namespace: "default", Name: "second"}
+	for h.Play(firstKey).Status.Phase != corev1alpha1.PlayRunning {
+		h.Reconcile(firstKey)
+	}
+
+	// Replica playing the maximum number of Plays doesn't start new ones
+	h.Reconcile(secondKey)
+	if result := h.Reconcile(secondKey); result.RequeueAfter == 0 {
+		t.Errorf("Expected Play over the limit to be requeued")
+	}
+	if phase := h.Play(secondKey).Status.Phase; phase != corev1alpha1.PlayCreated {
+		t.Errorf("Expected Play over the limit not to start, got %s", phase)
+	}
+
+	// Deleted Play doesn't count towards the limit
+	if err := h.Client.Delete(context.TODO(), h.Play(firstKey)); err != nil {
+		t.Fatal(err)
+	}
+	h.Reconcile(firstKey)
+	if value := testutil.ToFloat64(metrics.PlaysRunning); value != 0 {
+		t.Errorf("Expected no running Plays after the Play was deleted, got %v", value)
+	}
+	if result := h.Wait(secondKey); result.Status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Expected Play to complete, got %s", result.Status.Phase)
+	}
+}
+
+func TestFrameRuntime(t *testing.T) {
+	h := New(t)
+	other := fake.NewScheduler(h.Client)
+	h.Schedulers.Add("other", other)
+
+	remote := frame("b", "image")
+	remote.Runtime = "other"
+	result := h.RunPlay(newPlay("runtime",
+		corev1alpha1.Scene{Name: "build", Frames: []corev1alpha1.Frame{frame("a", "image"), remote}},
+	))
+
+	if result.Status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Expected Play to complete, got %s", result.Status.Phase)
+	}
+	if len(h.Scheduler.Runs()) != 1 || len(other.Runs()) != 1 {
+		t.Errorf("Frames not executed by their runtime: default %d, other %d", len(h.Scheduler.Runs()), len(other.Runs()))
+	}
+}
@@ -0,0 +1,262 @@
+// Package history records Plays, their scenes and frames in a database, so
+// results of Plays can be queried after the Plays are pruned from the cluster.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	// SQLite is the default dialect. Other dialects of gorm are available once
+	// their package is imported.
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultDialect is the gorm dialect of the history database
+	DefaultDialect = "sqlite3"
+	// TriggerManual is the trigger of Plays which weren't created by Kuberik
+	TriggerManual = "manual"
+)
+
+// Play is a recorded Play. Plays are identified by their UID, so Plays which
+// are recreated with the same name are recorded separately.
+type Play struct {
+	ID        uint   `gorm:"primary_key"`
+	UID       string `gorm:"unique_index"`
+	Namespace string `gorm:"index:idx_play_name"`
+	Name      string `gorm:"index:idx_play_name"`
+	Movie     string `gorm:"index"`
+	// Trigger is the source of the Play. It's screener:<name> for Plays of
+	// Screeners, upstream:<play> for Plays of Movie triggers,
+	// operator:<kind>/<name> for Plays of resources of operator Movies, api
+	// for Plays created through the API, or manual.
+	Trigger string
+	// Vars are the vars of the Play encoded as JSON
+	Vars       string `gorm:"type:text"`
+	Phase      string
+	StartedAt  time.Time `gorm:"index"`
+	FinishedAt *time.Time
+	Scenes     []Scene
+	Frames     []Frame
+}
+
+// Scene is a recorded scene of a Play
+type Scene struct {
+	ID         uint `gorm:"primary_key"`
+	PlayID     uint `gorm:"index"`
+	Name       string
+	Failed     bool
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// Frame is a recorded execution of a frame of a Play
+type Frame struct {
+	ID      uint `gorm:"primary_key"`
+	PlayID  uint `gorm:"index"`
+	Scene   string
+	FrameID string
+	Name    string `gorm:"index"`
+	Attempt int
+	// Execution is the name of the execution of the frame, such as a Job
+	Execution string
+	// ExitCode is nil until the frame finishes
+	ExitCode   *int
+	Reason     string
+	Message    string `gorm:"type:text"`
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// Duration returns how long the Play ran, or zero if it didn't finish
+func (p Play) Duration() time.Duration {
+	if p.FinishedAt == nil {
+		return 0
+	}
+	return p.FinishedAt.Sub(p.StartedAt)
+}
+
+// Store records history of Plays in a database
+type Store struct {
+	db *gorm.DB
+}
+
+// Open opens the history database of the gorm dialect, such as sqlite3 or
+// postgres, and migrates its schema
+func Open(dialect, dsn string) (*Store, error) {
+	db, err := gorm.Open(dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dialect == DefaultDialect {
+		// SQLite doesn't handle concurrent writes and in-memory databases
+		// exist only for a single connection
+		db.DB().SetMaxOpenConns(1)
+	}
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New creates a Store on an open database and migrates its schema
+func New(db *gorm.DB) (*Store, error) {
+	if err := db.AutoMigrate(&Play{}, &Scene{}, &Frame{}).Error; err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB returns the database of the Store
+func (s *Store) DB() *gorm.DB {
+	return s.db
+}
+
+// RecordPlay records the Play if it wasn't recorded yet and returns its record.
+// Plays are unique by their UID, so replicas which record the same Play at
+// once share its record.
+func (s *Store) RecordPlay(play *corev1alpha1.Play) (*Play, error) {
+	vars, err := json.Marshal(play.Spec.Vars)
+	if err != nil {
+		return nil, err
+	}
+	trigger := playTrigger(play)
+	startedAt := play.CreationTimestamp.Time
+	if play.Status.StartTime != nil {
+		startedAt = play.Status.StartTime.Time
+	}
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+	record := &Play{
+		UID:       playUID(play),
+		Namespace: play.Namespace,
+		Name:      play.Name,
+		Movie:     screener.MovieName(play),
+		Trigger:   trigger,
+		Vars:      string(vars),
+		Phase:     string(play.Status.Phase),
+		StartedAt: startedAt,
+	}
+	recorded := &Play{}
+	err = s.db.Where(Play{UID: record.UID}).Attrs(*record).FirstOrCreate(recorded).Error
+	if err != nil {
+		// Another replica might have recorded the Play after it wasn't found,
+		// so the insert violated the unique index of UIDs. Errors of the
+		// violation differ between dialects, so the record is read again.
+		if s.db.Where(Play{UID: record.UID}).First(recorded).Error == nil {
+			return recorded, nil
+		}
+		return nil, err
+	}
+	return recorded, nil
+}
+
+// playTrigger returns the source of the Play
+func playTrigger(play *corev1alpha1.Play) string {
+	if name := screener.ScreenerName(play); name != "" {
+		return "screener:" + name
+	}
+	if upstream, ok := play.Annotations[screener.UpstreamAnnotation]; ok {
+		return "upstream:" + upstream
+	}
+	if owner := metav1.GetControllerOf(play); owner != nil {
+		return fmt.Sprintf("operator:%s/%s", owner.Kind, owner.Name)
+	}
+	if trigger, ok := play.Annotations[screener.TriggerAnnotation]; ok {
+		return trigger
+	}
+	return TriggerManual
+}
+
+// playUID identifies the Play. Plays run without a cluster don't have a UID,
+// so they are identified by their name.
+func playUID(play *corev1alpha1.Play) string {
+	if play.UID != "" {
+		return string(play.UID)
+	}
+	return play.Namespace + "/" + play.Name
+}
+
+// RecordPlayFinished records the final phase of the Play
+func (s *Store) RecordPlayFinished(play *corev1alpha1.Play, phase corev1alpha1.PlayPhaseType, at time.Time) error {
+	record, err := s.RecordPlay(play)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(record).Updates(map[string]interface{}{"phase": string(phase), "finished_at": at}).Error
+}
+
+// RecordSceneStarted records the start of the scene of the Play
+func (s *Store) RecordSceneStarted(play *corev1alpha1.Play, scene string, at time.Time) error {
+	record, err := s.RecordPlay(play)
+	if err != nil {
+		return err
+	}
+	return s.db.Where(Scene{PlayID: record.ID, Name: scene}).Attrs(Scene{StartedAt: at}).FirstOrCreate(&Scene{}).Error
+}
+
+// RecordSceneFinished records the end of the scene of the Play
+func (s *Store) RecordSceneFinished(play *corev1alpha1.Play, scene string, failed bool, at time.Time) error {
+	record, err := s.RecordPlay(play)
+	if err != nil {
+		return err
+	}
+	sceneRecord := &Scene{}
+	if err := s.db.Where(Scene{PlayID: record.ID, Name: scene}).Attrs(Scene{StartedAt: at}).FirstOrCreate(sceneRecord).Error; err != nil {
+		return err
+	}
+	return s.db.Model(sceneRecord).Updates(map[string]interface{}{"failed": failed, "finished_at": at}).Error
+}
+
+// RecordFrameStarted records the start of an attempt of the frame. Executions
+// which are recovered keep the time when they were first recorded.
+func (s *Store) RecordFrameStarted(play *corev1alpha1.Play, scene string, frame *corev1alpha1.Frame, attempt int, execution string, at time.Time) error {
+	record, err := s.RecordPlay(play)
+	if err != nil {
+		return err
+	}
+	where := map[string]interface{}{"play_id": record.ID, "frame_id": frame.ID, "attempt": attempt}
+	return s.db.Where(where).Attrs(Frame{
+		Scene:     scene,
+		Name:      frame.Name,
+		Execution: execution,
+		StartedAt: at,
+	}).FirstOrCreate(&Frame{}).Error
+}
+
+// RecordFrameFinished records the result of the last attempt of the frame
+func (s *Store) RecordFrameFinished(play *corev1alpha1.Play, frameID string, exitCode int, failure *corev1alpha1.FrameFailure, at time.Time) error {
+	record, err := s.RecordPlay(play)
+	if err != nil {
+		return err
+	}
+	frameRecord := &Frame{}
+	err = s.db.Where("play_id = ? AND frame_id = ?", record.ID, frameID).Order("attempt desc").First(frameRecord).Error
+	if gorm.IsRecordNotFoundError(err) {
+		// The frame was started before the history was recorded
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{"exit_code": exitCode, "finished_at": at}
+	if failure != nil {
+		updates["reason"] = failure.Reason
+		updates["message"] = failure.Message
+	}
+	return s.db.Model(frameRecord).Updates(updates).Error
+}
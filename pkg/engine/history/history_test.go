@@ -0,0 +1,159 @@
+package history_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/history"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func open(t *testing.T) *history.Store {
+	store, err := history.Open(history.DefaultDialect, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// record records a Play of the Movie with a single frame which finished with the exit code
+func record(t *testing.T, store *history.Store, movie string, i int, duration time.Duration, exitCode int) {
+	started := metav1.NewTime(epoch.Add(time.Duration(i) * time.Hour))
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", movie, i),
+			Namespace: "default",
+			UID:       types.UID(fmt.Sprintf("%s-%d", movie, i)),
+			Labels:    map[string]string{screener.MovieLabel: movie},
+		},
+		Status: corev1alpha1.PlayStatus{StartTime: &started},
+	}
+	frame := &corev1alpha1.Frame{ID: "build-0", Name: "build"}
+	phase := corev1alpha1.PlayComplete
+	if exitCode != 0 {
+		phase = corev1alpha1.PlayFailed
+	}
+	for _, err := range []error{
+		store.RecordSceneStarted(play, "build", started.Time),
+		store.RecordFrameStarted(play, "build", frame, 0, play.Name+"-build", started.Time),
+		store.RecordFrameFinished(play, frame.ID, exitCode, nil, started.Add(duration)),
+		store.RecordSceneFinished(play, "build", exitCode != 0, started.Add(duration)),
+		store.RecordPlayFinished(play, phase, started.Add(duration)),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRecordPlay(t *testing.T) {
+	store := open(t)
+	defer store.Close()
+	record(t, store, "app", 0, time.Minute, 3)
+
+	play := history.Play{}
+	if err := store.DB().Preload("Scenes").Preload("Frames").First(&play).Error; err != nil {
+		t.Fatal(err)
+	}
+	if play.Trigger != history.TriggerManual || play.Movie != "app" || play.Phase != string(corev1alpha1.PlayFailed) {
+		t.Errorf("Unexpected record of the Play: %+v", play)
+	}
+	if play.Duration() != time.Minute {
+		t.Errorf("Expected Play to run for a minute, got %s", play.Duration())
+	}
+	if len(play.Scenes) != 1 || !play.Scenes[0].Failed {
+		t.Errorf("Expected a failed scene, got %+v", play.Scenes)
+	}
+	if len(play.Frames) != 1 || play.Frames[0].ExitCode == nil || *play.Frames[0].ExitCode != 3 {
+		t.Errorf("Expected a frame with exit code 3, got %+v", play.Frames)
+	}
+
+	// Play which was already recorded keeps its record
+	recorded, err := store.RecordPlay(&corev1alpha1.Play{ObjectMeta: metav1.ObjectMeta{Name: "app-0", Namespace: "default", UID: "app-0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	store.DB().Model(&history.Play{}).Count(&count)
+	if recorded.ID != play.ID || recorded.Phase != play.Phase || count != 1 {
+		t.Errorf("Expected the existing record %d of the Play, got %+v of %d records", play.ID, recorded, count)
+	}
+}
+
+func TestRecordPlayTrigger(t *testing.T) {
+	store := open(t)
+	defer store.Close()
+	controller := true
+	for i, test := range []struct {
+		meta    metav1.ObjectMeta
+		trigger string
+	}{
+		{metav1.ObjectMeta{}, history.TriggerManual},
+		{metav1.ObjectMeta{Annotations: map[string]string{screener.ScreenerAnnotation: "master"}}, "screener:master"},
+		{metav1.ObjectMeta{Annotations: map[string]string{screener.UpstreamAnnotation: "build-x7k2p"}}, "upstream:build-x7k2p"},
+		{metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: "Database", Name: "orders", Controller: &controller}}}, "operator:Database/orders"},
+		{metav1.ObjectMeta{Annotations: map[string]string{screener.TriggerAnnotation: "api"}}, "api"},
+	} {
+		test.meta.Name, test.meta.Namespace = fmt.Sprintf("app-%d", i), "default"
+		recorded, err := store.RecordPlay(&corev1alpha1.Play{ObjectMeta: test.meta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recorded.Trigger != test.trigger {
+			t.Errorf("Expected trigger %s of Play %s, got %s", test.trigger, test.meta.Name, recorded.Trigger)
+		}
+	}
+}
+
+func TestQueries(t *testing.T) {
+	store := open(t)
+	defer store.Close()
+	for i, exit := range []int{0, 1, 0, 0} {
+		record(t, store, "flaky", i, time.Duration(i+1)*time.Minute, exit)
+	}
+	for i := 0; i < 3; i++ {
+		record(t, store, "stable", i, time.Minute, 0)
+	}
+
+	trend, err := store.DurationTrend("flaky", epoch, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTrend := []history.DurationPoint{
+		{Start: epoch, Plays: 1, Average: time.Minute, Max: time.Minute},
+		{Start: epoch.Add(2 * time.Hour), Plays: 2, Average: 3*time.Minute + 30*time.Second, Max: 4 * time.Minute},
+	}
+	if fmt.Sprint(trend) != fmt.Sprint(expectedTrend) {
+		t.Errorf("Expected duration trend %v, got %v", expectedTrend, trend)
+	}
+
+	if _, err := store.DurationTrend("flaky", epoch, 0); err == nil {
+		t.Error("Expected duration trend of an empty interval to fail")
+	}
+
+	flakiness, err := store.Flakiness("flaky", epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flakiness) != 1 || flakiness[0].Runs != 4 || flakiness[0].Flips != 2 || flakiness[0].Failures != 1 {
+		t.Errorf("Unexpected flakiness: %+v", flakiness)
+	}
+
+	rates, err := store.FailureRates(epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRates := []history.FailureRate{
+		{Movie: "flaky", Plays: 4, Failures: 1, Rate: 0.25},
+		{Movie: "stable", Plays: 3},
+	}
+	if fmt.Sprint(rates) != fmt.Sprint(expectedRates) {
+		t.Errorf("Expected failure rates %v, got %v", expectedRates, rates)
+	}
+}
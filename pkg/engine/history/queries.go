@@ -0,0 +1,135 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+)
+
+// DurationPoint is the duration of Plays which started in an interval
+type DurationPoint struct {
+	Start   time.Time
+	Plays   int
+	Average time.Duration
+	Max     time.Duration
+}
+
+// FrameFlakiness describes how often results of a frame change between
+// consecutive runs. Flakiness is the ratio of changes to possible changes, so
+// a frame which alternates between success and failure has flakiness 1.
+type FrameFlakiness struct {
+	Frame     string
+	Runs      int
+	Failures  int
+	Flips     int
+	Flakiness float64
+}
+
+// FailureRate is the ratio of finished Plays of a Movie which failed or errored
+type FailureRate struct {
+	Movie    string
+	Plays    int
+	Failures int
+	Rate     float64
+}
+
+// DurationTrend returns durations of completed Plays of the Movie which started
+// since the time, grouped by intervals of the given length, which must be
+// positive. Intervals without Plays are omitted.
+func (s *Store) DurationTrend(movie string, since time.Time, interval time.Duration) ([]DurationPoint, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("Interval of the duration trend must be positive, got %s", interval)
+	}
+	var plays []Play
+	err := s.db.Where("movie = ? AND phase = ? AND started_at >= ? AND finished_at IS NOT NULL", movie, string(corev1alpha1.PlayComplete), since).
+		Order("started_at").Find(&plays).Error
+	if err != nil {
+		return nil, err
+	}
+	var points []DurationPoint
+	var total time.Duration
+	for _, play := range plays {
+		start := since.Add(play.StartedAt.Sub(since) / interval * interval)
+		if len(points) == 0 || !points[len(points)-1].Start.Equal(start) {
+			points = append(points, DurationPoint{Start: start})
+			total = 0
+		}
+		point := &points[len(points)-1]
+		duration := play.Duration()
+		total += duration
+		point.Plays++
+		point.Average = total / time.Duration(point.Plays)
+		if duration > point.Max {
+			point.Max = duration
+		}
+	}
+	return points, nil
+}
+
+// Flakiness returns flakiness of frames of the Movie in Plays which started
+// since the time, from the most flaky frame. Frames are compared by name, since
+// IDs of frames change when screenplays are edited.
+func (s *Store) Flakiness(movie string, since time.Time) ([]FrameFlakiness, error) {
+	var frames []Frame
+	err := s.db.Table("frames").Select("frames.*").
+		Joins("JOIN plays ON plays.id = frames.play_id").
+		Where("plays.movie = ? AND plays.started_at >= ? AND frames.exit_code IS NOT NULL", movie, since).
+		Order("plays.started_at, frames.attempt").Find(&frames).Error
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*FrameFlakiness)
+	lastFailed := make(map[string]bool)
+	var result []*FrameFlakiness
+	for _, frame := range frames {
+		f, ok := byName[frame.Name]
+		if !ok {
+			f = &FrameFlakiness{Frame: frame.Name}
+			byName[frame.Name] = f
+			result = append(result, f)
+		}
+		failed := *frame.ExitCode != 0 || frame.Reason != ""
+		if f.Runs > 0 && failed != lastFailed[frame.Name] {
+			f.Flips++
+		}
+		lastFailed[frame.Name] = failed
+		f.Runs++
+		if failed {
+			f.Failures++
+		}
+	}
+	flakiness := make([]FrameFlakiness, 0, len(result))
+	for _, f := range result {
+		if f.Runs > 1 {
+			f.Flakiness = float64(f.Flips) / float64(f.Runs-1)
+		}
+		flakiness = append(flakiness, *f)
+	}
+	sort.SliceStable(flakiness, func(i, j int) bool { return flakiness[i].Flakiness > flakiness[j].Flakiness })
+	return flakiness, nil
+}
+
+// FailureRates returns failure rates of Movies whose Plays finished since the
+// time, ordered by Movie. Plays which weren't created from a Movie are skipped.
+func (s *Store) FailureRates(since time.Time) ([]FailureRate, error) {
+	var plays []Play
+	err := s.db.Select("movie, phase").Where("movie <> '' AND finished_at >= ?", since).Order("movie").Find(&plays).Error
+	if err != nil {
+		return nil, err
+	}
+	var rates []FailureRate
+	for _, play := range plays {
+		if len(rates) == 0 || rates[len(rates)-1].Movie != play.Movie {
+			rates = append(rates, FailureRate{Movie: play.Movie})
+		}
+		rate := &rates[len(rates)-1]
+		rate.Plays++
+		if phase := corev1alpha1.PlayPhaseType(play.Phase); phase == corev1alpha1.PlayFailed || phase == corev1alpha1.PlayError {
+			rate.Failures++
+		}
+		rate.Rate = float64(rate.Failures) / float64(rate.Plays)
+	}
+	return rates, nil
+}
@@ -0,0 +1,55 @@
+// Package metrics defines Prometheus metrics of Plays, scenes and frames. They
+// are registered with the registry of controller-runtime, so they're served
+// together with metrics of the manager.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// PlaysStarted counts Plays which started running, by Movie
+	PlaysStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kuberik_plays_started_total",
+		Help: "Number of Plays which started running",
+	}, []string{"movie"})
+	// PlaysFinished counts Plays which finished, by Movie and final phase
+	PlaysFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kuberik_plays_finished_total",
+		Help: "Number of Plays which finished",
+	}, []string{"movie", "phase"})
+	// PlaysRunning is the number of running Plays held by the operator replica
+	PlaysRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kuberik_plays_running",
+		Help: "Number of running Plays played by the replica",
+	})
+	// FrameDuration observes how long executions of frames ran, by frame name
+	FrameDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kuberik_frame_duration_seconds",
+		Help:    "Time from the start of an execution of a frame until it finished",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"frame"})
+	// FrameRetries counts frames which were started again after a failed attempt, by frame name
+	FrameRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kuberik_frame_retries_total",
+		Help: "Number of times frames were started again",
+	}, []string{"frame"})
+	// QueueWait observes how long executions waited for their first container to run
+	QueueWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kuberik_execution_queue_wait_seconds",
+		Help:    "Time from the creation of a Job of an execution until its Pod was running",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		PlaysStarted,
+		PlaysFinished,
+		PlaysRunning,
+		FrameDuration,
+		FrameRetries,
+		QueueWait,
+	)
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/metrics"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reasons of Events emitted on Plays. They are part of the API, so alerts can
+// be defined on them.
+const (
+	// ReasonPlayStarted means the Play started running
+	ReasonPlayStarted = "PlayStarted"
+	// ReasonPlayCompleted means all scenes of the Play finished successfully
+	ReasonPlayCompleted = "PlayCompleted"
+	// ReasonPlayFailed means a scene of the Play failed
+	ReasonPlayFailed = "PlayFailed"
+	// ReasonPlayErrored means the Play couldn't be played
+	ReasonPlayErrored = "PlayErrored"
+	// ReasonPlayCancelled means the Play was cancelled before it finished
+	ReasonPlayCancelled = "PlayCancelled"
+	// ReasonProvisioningFailed means objects of the Play, such as volumes, couldn't be provisioned
+	ReasonProvisioningFailed = "ProvisioningFailed"
+	// ReasonSceneStarted means the first frames of a scene started
+	ReasonSceneStarted = "SceneStarted"
+	// ReasonSceneFinished means all frames of a scene finished successfully or their errors were ignored
+	ReasonSceneFinished = "SceneFinished"
+	// ReasonSceneFailed means a frame of a scene failed
+	ReasonSceneFailed = "SceneFailed"
+	// ReasonFrameStarted means an execution of a frame started
+	ReasonFrameStarted = "FrameStarted"
+	// ReasonFrameRetried means a frame started again with a new attempt
+	ReasonFrameRetried = "FrameRetried"
+	// ReasonFrameFinished means a frame finished successfully
+	ReasonFrameFinished = "FrameFinished"
+	// ReasonFrameFailed means a frame failed. The message holds the cause of the failure.
+	ReasonFrameFailed = "FrameFailed"
+)
+
+// phaseEvents maps phases of Plays to types and reasons of their Events
+var phaseEvents = map[corev1alpha1.PlayPhaseType][2]string{
+	corev1alpha1.PlayRunning:  {corev1.EventTypeNormal, ReasonPlayStarted},
+	corev1alpha1.PlayComplete: {corev1.EventTypeNormal, ReasonPlayCompleted},
+	corev1alpha1.PlayFailed:   {corev1.EventTypeWarning, ReasonPlayFailed},
+	corev1alpha1.PlayError:    {corev1.EventTypeWarning, ReasonPlayErrored},
+}
+
+// PhaseEvent returns type and reason of the Event emitted when a Play enters the phase
+func PhaseEvent(phase corev1alpha1.PlayPhaseType) (string, string, bool) {
+	event, ok := phaseEvents[phase]
+	return event[0], event[1], ok
+}
+
+// frameStarted reports the start of an attempt of the frame as the execution with the name
+func (p *Player) frameStarted(play *corev1alpha1.Play, frame *corev1alpha1.Frame, name string, attempt int) {
+	if attempt > firstAttempt {
+		metrics.FrameRetries.WithLabelValues(frame.Name).Inc()
+		p.event(play, corev1.EventTypeNormal, ReasonFrameRetried, "Frame %s started again as %s", frame.Name, name)
+		return
+	}
+	p.event(play, corev1.EventTypeNormal, ReasonFrameStarted, "Frame %s started as %s", frame.Name, name)
+}
+
+// event emits an Event on the Play if the Player has a recorder
+func (p *Player) event(play *corev1alpha1.Play, eventType, reason, messageFmt string, args ...interface{}) {
+	if p.Recorder == nil {
+		return
+	}
+	p.Recorder.Eventf(play, eventType, reason, messageFmt, args...)
+}
@@ -1,16 +1,14 @@
 package runtime
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 
-	_ "github.com/jinzhu/gorm/dialects/sqlite"
-
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
 	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler"
-	"github.com/kuberik/kuberik/pkg/kubeutils"
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const (
@@ -18,106 +16,48 @@ const (
 	mainScreenplayName = "main"
 )
 
-func Play(livePlay corev1alpha1.Play) error {
-	var mainPlay *corev1alpha1.Screenplay
-	for i := range livePlay.Spec.Screenplays {
-		if livePlay.Spec.Screenplays[i].Name == mainScreenplayName {
-			mainPlay = &livePlay.Spec.Screenplays[i]
-		}
-	}
-	if mainPlay == nil {
+// Play executes the Play with Schedulers of the default registry
+func Play(ctx context.Context, livePlay corev1alpha1.Play) error {
+	return PlayWith(ctx, scheduler.DefaultRegistry, livePlay)
+}
+
+// PlayWith executes the Play with Schedulers of the registry without a
+// controller. The Play is reconciled by a Player whenever one of its executions
+// finishes, until the Play finishes or the context is done.
+func PlayWith(ctx context.Context, schedulers *scheduler.Registry, livePlay corev1alpha1.Play) error {
+	if mainScreenplay(&livePlay) == nil {
 		return fmt.Errorf("Play doesn't have a main screenplay")
 	}
-	populateVars(&livePlay.Spec, livePlay.Status.VarsConfigMap)
-	expandCopies(&livePlay.Spec)
-	expandProvisionedVolumes(&livePlay)
+	player := NewPlayer(schedulers)
+	player.StartTrace(&livePlay)
+	notify := make(chan struct{}, 1)
+	player.Notify = func(types.NamespacedName) {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
 	go func() {
-		success := true
-		for i, _ := range mainPlay.Scenes {
-			success = success && playScene(livePlay, &mainPlay.Scenes[i])
-			if !success {
-				break
+		for {
+			finished, err := player.Reconcile(ctx, &livePlay)
+			if err != nil {
+				log.Errorf("Failed to play %s: %s", livePlay.Name, err)
+				return
+			}
+			if finished {
+				return
+			}
+			select {
+			case <-notify:
+			case <-ctx.Done():
+				log.Infof("Stopped playing %s: %s", livePlay.Name, ctx.Err())
+				return
 			}
 		}
-
-		var playEnd corev1alpha1.PlayPhaseType
-		if success {
-			playEnd = corev1alpha1.PlayComplete
-		} else {
-			playEnd = corev1alpha1.PlayFailed
-		}
-		scheduler.Engine.UpdatePlayPhase(livePlay, playEnd)
 	}()
 	return nil
 }
 
-func playScene(livePlay corev1alpha1.Play, scene *corev1alpha1.Scene) bool {
-	// var exit int
-	exits := make(chan int)
-	for i, _ := range scene.Frames {
-		frame := scene.Frames[i]
-		go func() {
-			exit, _ := playFrame(livePlay, frame)
-			err := scheduler.Engine.UpdateFrameResult(livePlay, frame.ID, exit)
-			if err != nil {
-				log.Warn(fmt.Errorf("Updating frame result failed: %s", err))
-			}
-			exits <- exit
-		}()
-	}
-
-	exitTotal := 0
-	for _ = range scene.Frames {
-		exitTotal = <-exits | exitTotal
-	}
-
-	finalizeScene(livePlay, scene.Name, exitTotal)
-	if scene.IgnoreErrors {
-		return true
-	}
-	return exitTotal == 0
-}
-
-func playFrame(livePlay corev1alpha1.Play, frame corev1alpha1.Frame) (int, error) {
-	if exit, recovered := livePlay.Status.Frames[frame.ID]; recovered {
-		return exit, nil
-	}
-
-	// maximum string for job name is 63 characters.
-	executionName := fmt.Sprintf("%.29s-%.16s-%.16s", livePlay.Name, frame.Name, frame.ID)
-	output, result, err := scheduler.RunAsync(executionName, kubeutils.NamespaceObject(livePlay.Namespace), *frame.Action)
-	if err != nil {
-		log.Errorf("Failed to play frame (%s): %s", frame.Name, err)
-		scheduler.Engine.UpdatePlayPhase(livePlay, corev1alpha1.PlayError)
-		return 1, err
-	}
-	buffer := bufio.NewReaderSize(output, 32*1024)
-	for {
-		line, _, err := buffer.ReadLine()
-
-		if err != nil {
-			break
-		}
-		log.Infof("Task %s: %s", frame.Name, line)
-	}
-	exit := <-result
-	if exit != 0 && frame.IgnoreErrors {
-		exit = 0
-	}
-	return exit, nil
-}
-
-func finalizeScene(livePlay corev1alpha1.Play, sceneName string, exit int) {
-	if exit != 0 {
-		scheduler.Engine.UpdatePlayPhase(livePlay, corev1alpha1.PlayFailed)
-	}
-
-	// Scene failed so don't proceed onto the next one.
-	if exit != 0 {
-		return
-	}
-}
-
 func expandCopies(playSpec *corev1alpha1.PlaySpec) {
 	for k := range playSpec.Screenplays {
 		for si := range playSpec.Screenplays[k].Scenes {
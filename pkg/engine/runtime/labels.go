@@ -0,0 +1,110 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PlayLabel is the label holding the name of the Play an object belongs to.
+	// Names of Plays are cut to the length of label values, so it's only
+	// informational and objects of a Play are selected by PlayUIDLabel.
+	PlayLabel = "core.kuberik.io/play"
+	// PlayUIDLabel is the label holding the UID of the Play an object belongs to
+	PlayUIDLabel = "core.kuberik.io/play-uid"
+	// SceneLabel is the label holding the name of the scene an execution belongs to
+	SceneLabel = "core.kuberik.io/scene"
+	// FrameLabel is the label holding the ID of the frame an execution belongs to
+	FrameLabel = "core.kuberik.io/frame"
+	// AttemptLabel is the label holding the attempt of the frame an execution runs
+	AttemptLabel = "core.kuberik.io/attempt"
+	// FrameNameAnnotation is the annotation holding the name of the frame an execution belongs to
+	FrameNameAnnotation = "core.kuberik.io/frame-name"
+	// SceneNameAnnotation is the annotation holding the name of the scene an execution belongs to
+	SceneNameAnnotation = "core.kuberik.io/scene-name"
+
+	// frames are played once per Play
+	firstAttempt = 0
+	// length of the readable prefix of execution names, leaving room for the
+	// hash and suffixes Kubernetes adds to names of Pods
+	maxExecutionPrefixLength = 40
+	executionHashLength      = 10
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// PlayLabels returns labels of objects created for the Play
+func PlayLabels(play *corev1alpha1.Play) map[string]string {
+	labels := PlaySelector(play)
+	labels[PlayLabel] = kubeutils.LabelValue(play.Name)
+	if movie, ok := play.Labels[screener.MovieLabel]; ok {
+		labels[screener.MovieLabel] = movie
+	}
+	return labels
+}
+
+// PlaySelector returns labels selecting objects created for the Play. Objects
+// are selected by the UID of the Play, since names of Plays which share a
+// prefix can't be told apart in label values. Plays which don't exist on the
+// cluster don't have a UID, so they are selected by their name.
+func PlaySelector(play *corev1alpha1.Play) map[string]string {
+	if play.UID == "" {
+		return map[string]string{PlayLabel: kubeutils.LabelValue(play.Name)}
+	}
+	return map[string]string{PlayUIDLabel: string(play.UID)}
+}
+
+// ExecutionName returns the name of the execution of the frame. Name is a
+// readable prefix of Play and frame names followed by a hash of the Play UID,
+// frame ID and attempt, so it's a valid DNS label which doesn't collide with
+// executions of other frames and is the same when the Play is recovered.
+func ExecutionName(play *corev1alpha1.Play, frame *corev1alpha1.Frame, attempt int) string {
+	// Plays which don't exist on the cluster don't have a UID
+	playID := string(play.UID)
+	if playID == "" {
+		playID = fmt.Sprintf("%s/%s", play.Namespace, play.Name)
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", playID, frame.ID, attempt)))
+
+	prefix := invalidNameChars.ReplaceAllString(strings.ToLower(fmt.Sprintf("%s-%s", play.Name, frame.Name)), "-")
+	if len(prefix) > maxExecutionPrefixLength {
+		prefix = prefix[:maxExecutionPrefixLength]
+	}
+	prefix = strings.Trim(prefix, "-")
+	name := hex.EncodeToString(hash[:])[:executionHashLength]
+	if prefix != "" {
+		name = fmt.Sprintf("%s-%s", prefix, name)
+	}
+	return name
+}
+
+// executionMeta returns metadata of the execution of the frame. Executions are
+// controlled by the Play if it exists on the cluster.
+func executionMeta(play *corev1alpha1.Play, scene *corev1alpha1.Scene, frame *corev1alpha1.Frame, attempt int) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Name:      ExecutionName(play, frame, attempt),
+		Namespace: play.Namespace,
+		Labels:    PlayLabels(play),
+		Annotations: map[string]string{
+			FrameNameAnnotation: frame.Name,
+			SceneNameAnnotation: scene.Name,
+		},
+	}
+	meta.Labels[SceneLabel] = kubeutils.LabelValue(scene.Name)
+	meta.Labels[FrameLabel] = kubeutils.LabelValue(frame.ID)
+	meta.Labels[AttemptLabel] = fmt.Sprintf("%d", attempt)
+	if play.UID != "" {
+		meta.OwnerReferences = []metav1.OwnerReference{
+			*metav1.NewControllerRef(play, corev1alpha1.SchemeGroupVersion.WithKind("Play")),
+		}
+	}
+	return meta
+}
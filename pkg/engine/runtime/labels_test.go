@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestExecutionName(t *testing.T) {
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: strings.Repeat("very-long-play-name", 5),
+			UID:  "6b8a3c1e-52f7-4f0e-9d1a-0c2b5e7f9a31",
+		},
+	}
+	frames := []*corev1alpha1.Frame{
+		{Name: "Build_Image", ID: "a"},
+		{Name: "Build_Image", ID: "b"},
+		{Name: strings.Repeat("x", 100) + "-", ID: "c"},
+	}
+
+	names := make(map[string]bool)
+	for _, frame := range frames {
+		name := ExecutionName(play, frame, firstAttempt)
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			t.Errorf("Invalid execution name %s: %v", name, errs)
+		}
+		if names[name] {
+			t.Errorf("Execution name %s is not unique", name)
+		}
+		names[name] = true
+		if ExecutionName(play, frame, firstAttempt) != name {
+			t.Errorf("Execution name of frame %s is not deterministic", frame.ID)
+		}
+		if ExecutionName(play, frame, firstAttempt+1) == name {
+			t.Errorf("Attempts of frame %s have the same execution name", frame.ID)
+		}
+	}
+
+	other := play.DeepCopy()
+	other.UID = "0f0c1b5a-7a4e-4c0e-8a6b-2d3e4f5a6b7c"
+	if ExecutionName(other, frames[0], firstAttempt) == ExecutionName(play, frames[0], firstAttempt) {
+		t.Errorf("Executions of Plays with the same name have the same name")
+	}
+}
+
+func TestPlaySelector(t *testing.T) {
+	prefix := strings.Repeat("movie-", 11)
+	play := &corev1alpha1.Play{ObjectMeta: metav1.ObjectMeta{Name: prefix + "upstream-abcde", UID: "6b8a3c1e-52f7-4f0e-9d1a-0c2b5e7f9a31"}}
+	other := &corev1alpha1.Play{ObjectMeta: metav1.ObjectMeta{Name: prefix + "upstream-fghij", UID: "0f0c1b5a-7a4e-4c0e-8a6b-2d3e4f5a6b7c"}}
+
+	if PlayLabels(play)[PlayLabel] != PlayLabels(other)[PlayLabel] {
+		t.Fatalf("Expected names of the Plays to be cut to the same label value")
+	}
+	selector := labels.SelectorFromSet(PlaySelector(play))
+	if !selector.Matches(labels.Set(PlayLabels(play))) {
+		t.Errorf("Expected selector %s to match labels of the Play", selector)
+	}
+	if selector.Matches(labels.Set(PlayLabels(other))) {
+		t.Errorf("Expected selector %s not to match labels of another Play with the same prefix", selector)
+	}
+	for k, v := range PlayLabels(play) {
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			t.Errorf("Invalid value of label %s: %v", k, errs)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package runtime
+
+import (
+	"context"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FrameOutputs returns outputs of all frames of the Play which have one,
+// indexed by frame name. Output of a frame is the termination message of its
+// container, see https://kubernetes.io/docs/tasks/debug-application-cluster/determine-reason-pod-failure/
+func FrameOutputs(c client.Client, play *corev1alpha1.Play) (map[string]string, error) {
+	spec := play.Spec.DeepCopy()
+	expandCopies(spec)
+
+	outputs := make(map[string]string)
+	for _, screenplay := range spec.Screenplays {
+		for _, scene := range screenplay.Scenes {
+			for i := range scene.Frames {
+				frame := &scene.Frames[i]
+				if _, finished := play.Status.Frames[frame.ID]; !finished {
+					continue
+				}
+				// Pods are found by labels of the execution, since their names are generated
+				pods := &corev1.PodList{}
+				selector := PlaySelector(play)
+				selector[FrameLabel] = kubeutils.LabelValue(frame.ID)
+				err := c.List(context.TODO(), pods, client.InNamespace(play.Namespace), client.MatchingLabels(selector))
+				if err != nil {
+					return nil, err
+				}
+				if output, ok := terminationMessage(pods.Items); ok {
+					outputs[frame.Name] = output
+				}
+			}
+		}
+	}
+	return outputs, nil
+}
+
+func terminationMessage(pods []corev1.Pod) (string, bool) {
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil && status.State.Terminated.Message != "" {
+				return status.State.Terminated.Message, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,468 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/history"
+	"github.com/kuberik/kuberik/pkg/engine/metrics"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	"github.com/kuberik/kuberik/pkg/eventbus"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// Player plays Plays level-triggered. Every reconciliation computes the next
+// runnable frames from the status of the Play and executions known to the
+// Schedulers, starts executions which are missing and records results of the
+// finished ones. Executions have deterministic names, so Schedulers which
+// outlive the Player, such as Kubernetes, recover them instead of running the
+// frames again, and report their status to any Player holding the Play.
+type Player struct {
+	schedulers *scheduler.Registry
+	// Notify is called with the Play when one of its executions finishes. It can be nil.
+	Notify func(types.NamespacedName)
+	// Recorder emits Events on Plays. Events aren't emitted if it's nil.
+	Recorder record.EventRecorder
+	// History records Plays, scenes and frames. History isn't recorded if it's nil.
+	History *history.Store
+
+	lock sync.Mutex
+	// executions started by the Player, keyed by Play and frame ID
+	executions map[types.NamespacedName]map[string]execution.Execution
+	// failed attempts of observed executions, keyed by Play and frame ID
+	failures map[types.NamespacedName]map[string]int
+	// spans of Plays which are being played
+	traces map[types.NamespacedName]*playTrace
+}
+
+// NewPlayer creates a Player which runs frames on Schedulers of the registry,
+// emits Events with the recorder of the engine and records history in its store
+func NewPlayer(schedulers *scheduler.Registry) *Player {
+	return &Player{
+		schedulers: schedulers,
+		Recorder:   config.Recorder,
+		History:    config.History,
+		executions: make(map[types.NamespacedName]map[string]execution.Execution),
+		failures:   make(map[types.NamespacedName]map[string]int),
+		traces:     make(map[types.NamespacedName]*playTrace),
+	}
+}
+
+// Reconcile plays the next runnable frames of the Play and returns whether the
+// Play finished. Frames run on the Scheduler selected by their runtime and the
+// phase of the Play is recorded by the default Scheduler. Recorded results are
+// also set on the status of the Play. Executions are started with the context,
+// so they stop being watched when it's done.
+func (p *Player) Reconcile(ctx context.Context, play *corev1alpha1.Play) (bool, error) {
+	engine, err := p.schedulers.Get("")
+	if err != nil {
+		return false, err
+	}
+	livePlay := play.DeepCopy()
+	mainPlay := mainScreenplay(livePlay)
+	if mainPlay == nil {
+		return false, fmt.Errorf("Play doesn't have a main screenplay")
+	}
+	populateVars(&livePlay.Spec, livePlay.Status.VarsConfigMap)
+	expandCopies(&livePlay.Spec)
+	expandProvisionedVolumes(livePlay)
+	observed, err := p.observe(ctx, livePlay, mainPlay)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range mainPlay.Scenes {
+		scene := &mainPlay.Scenes[i]
+		running, failed := false, false
+		// whether frames of the scene were played before and whether any were recorded now
+		begun, recordedNow := false, false
+		// frames which don't have an execution yet
+		var pending []*corev1alpha1.Frame
+		for j := range scene.Frames {
+			frame := &scene.Frames[j]
+			exit, recorded := play.Status.Frames[frame.ID]
+			if !recorded {
+				observation, exists, err := p.frameStatus(ctx, livePlay, scene, frame, observed)
+				if err != nil {
+					log.Errorf("Failed to play frame (%s): %s", frame.Name, err)
+					return true, p.finish(ctx, engine, play, corev1alpha1.PlayError)
+				}
+				if !exists {
+					pending = append(pending, frame)
+					continue
+				}
+				begun = true
+				if !observation.Finished {
+					running = true
+					continue
+				}
+				if err := p.recordFrameResult(ctx, play, frame, observation); err != nil {
+					return false, err
+				}
+				recordedNow = true
+				exit = observation.ExitCode
+			}
+			begun = true
+			failed = failed || exit != 0
+		}
+		if len(pending) > 0 {
+			if !begun {
+				p.event(play, corev1.EventTypeNormal, ReasonSceneStarted, "Scene %s started", scene.Name)
+				p.recordHistory(play, func(h *history.Store) error {
+					return h.RecordSceneStarted(play, scene.Name, time.Now())
+				})
+			}
+			for _, frame := range pending {
+				if err := p.startFrame(ctx, livePlay, scene, frame); err != nil {
+					log.Errorf("Failed to play frame (%s): %s", frame.Name, err)
+					return true, p.finish(ctx, engine, play, corev1alpha1.PlayError)
+				}
+			}
+			running = true
+		}
+		if running {
+			return false, nil
+		}
+		if recordedNow {
+			p.endSceneSpan(play, scene.Name, failed && !scene.IgnoreErrors)
+			p.recordHistory(play, func(h *history.Store) error {
+				return h.RecordSceneFinished(play, scene.Name, failed && !scene.IgnoreErrors, time.Now())
+			})
+			if failed && !scene.IgnoreErrors {
+				p.event(play, corev1.EventTypeWarning, ReasonSceneFailed, "Scene %s failed", scene.Name)
+			} else {
+				p.event(play, corev1.EventTypeNormal, ReasonSceneFinished, "Scene %s finished", scene.Name)
+			}
+		}
+		if failed && !scene.IgnoreErrors {
+			return true, p.finish(ctx, engine, play, corev1alpha1.PlayFailed)
+		}
+	}
+	return true, p.finish(ctx, engine, play, corev1alpha1.PlayComplete)
+}
+
+// observe returns executions of the Play observed by Schedulers of its frames
+// which implement scheduler.Observer, keyed by runtime and execution name
+func (p *Player) observe(ctx context.Context, play *corev1alpha1.Play, screenplay *corev1alpha1.Screenplay) (map[string]map[string]execution.Observation, error) {
+	observed := make(map[string]map[string]execution.Observation)
+	for _, scene := range screenplay.Scenes {
+		for _, frame := range scene.Frames {
+			if _, ok := observed[frame.Runtime]; ok {
+				continue
+			}
+			// Unknown runtimes fail the Play once the frame is played
+			engine, err := p.schedulers.Get(frame.Runtime)
+			if err != nil {
+				continue
+			}
+			observer, ok := engine.(scheduler.Observer)
+			if !ok {
+				continue
+			}
+			observations, err := observer.Observe(ctx, play.Namespace, PlaySelector(play))
+			if err != nil {
+				return nil, fmt.Errorf("Observing executions failed: %s", err)
+			}
+			observed[frame.Runtime] = observations
+		}
+	}
+	return observed, nil
+}
+
+// frameStatus returns the observed execution of the frame and whether it
+// exists. Executions of Schedulers implementing scheduler.Observer are
+// observed on the Scheduler, so executions started by other replicas are
+// followed only for their logs. Executions of other Schedulers are observed by
+// their handles. Failures of frames which ignore errors are reported with a
+// zero exit code, but keep the cause of the failure.
+func (p *Player) frameStatus(ctx context.Context, livePlay *corev1alpha1.Play, scene *corev1alpha1.Scene, frame *corev1alpha1.Frame, observed map[string]map[string]execution.Observation) (execution.Observation, bool, error) {
+	engine, err := p.schedulers.Get(frame.Runtime)
+	if err != nil {
+		return execution.Observation{}, false, err
+	}
+	key := types.NamespacedName{Namespace: livePlay.Namespace, Name: livePlay.Name}
+	e := p.execution(key, frame.ID)
+	var observation execution.Observation
+	if _, ok := engine.(scheduler.Observer); ok {
+		name := ExecutionName(livePlay, frame, firstAttempt)
+		var exists bool
+		observation, exists = observed[frame.Runtime][name]
+		if !exists {
+			// Execution started by the Player might not be observed yet
+			return execution.Observation{}, e != nil, nil
+		}
+		if e == nil {
+			if _, err := p.run(ctx, livePlay, scene, frame); err != nil {
+				log.Warnf("Failed to follow execution of frame (%s): %s", frame.Name, err)
+			}
+		}
+		p.observeRetries(livePlay, frame, name, observation)
+	} else {
+		if e == nil {
+			return execution.Observation{}, false, nil
+		}
+		observation.Status = e.Status()
+	}
+
+	if !observation.Finished {
+		return observation, true, nil
+	}
+	// Events can't be published when running without a cluster
+	if observation.ExitCode == 0 && frame.Publish != nil && config.Client != nil {
+		if err := eventbus.Publish(config.Client, eventbus.NewFrameEvent(livePlay, frame)); err != nil {
+			log.Errorf("Failed to publish event of frame (%s): %s", frame.Name, err)
+		}
+	}
+	if observation.ExitCode != 0 {
+		if observation.Reason == "" {
+			observation.Reason = execution.ReasonError
+			observation.Message = fmt.Sprintf("Exited with code %d", observation.ExitCode)
+		}
+		if frame.IgnoreErrors {
+			observation.ExitCode = 0
+		}
+	}
+	return observation, true, nil
+}
+
+// startFrame starts the execution of the frame and reports its start
+func (p *Player) startFrame(ctx context.Context, livePlay *corev1alpha1.Play, scene *corev1alpha1.Scene, frame *corev1alpha1.Frame) error {
+	name, err := p.run(ctx, livePlay, scene, frame)
+	if err != nil {
+		return err
+	}
+	p.setFailures(types.NamespacedName{Namespace: livePlay.Namespace, Name: livePlay.Name}, frame.ID, 0)
+	p.frameStarted(livePlay, frame, name, firstAttempt)
+	p.recordHistory(livePlay, func(h *history.Store) error {
+		return h.RecordFrameStarted(livePlay, scene.Name, frame, firstAttempt, name, time.Now())
+	})
+	return nil
+}
+
+// observeRetries reports attempts of the frame which started since the last
+// observation, after earlier attempts of its running execution failed.
+// Attempts of recovered executions are reported only once they are observed
+// again, since earlier attempts were reported by the replica which started them.
+func (p *Player) observeRetries(livePlay *corev1alpha1.Play, frame *corev1alpha1.Frame, name string, observation execution.Observation) {
+	key := types.NamespacedName{Namespace: livePlay.Namespace, Name: livePlay.Name}
+	p.lock.Lock()
+	known, ok := p.failures[key][frame.ID]
+	p.lock.Unlock()
+	p.setFailures(key, frame.ID, observation.Failed)
+	if !ok || observation.Finished {
+		return
+	}
+	for attempt := known + 1; attempt <= observation.Failed; attempt++ {
+		p.frameStarted(livePlay, frame, name, attempt)
+	}
+}
+
+func (p *Player) setFailures(key types.NamespacedName, frameID string, failed int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.failures[key] == nil {
+		p.failures[key] = make(map[string]int)
+	}
+	p.failures[key][frameID] = failed
+}
+
+// run runs the execution of the frame on its Scheduler and follows it. Running
+// an execution which already exists recovers it.
+func (p *Player) run(ctx context.Context, livePlay *corev1alpha1.Play, scene *corev1alpha1.Scene, frame *corev1alpha1.Frame) (string, error) {
+	engine, err := p.schedulers.Get(frame.Runtime)
+	if err != nil {
+		return "", err
+	}
+	key := types.NamespacedName{Namespace: livePlay.Namespace, Name: livePlay.Name}
+	meta := executionMeta(livePlay, scene, frame, firstAttempt)
+	frameCtx := p.startFrameSpan(ctx, livePlay, scene, frame, meta.Name)
+	e, err := engine.Run(frameCtx, meta, *withTraceparent(frameCtx, frame.Action))
+	if err != nil {
+		return "", err
+	}
+	p.track(key, frame.ID, e)
+	// Durations of observed executions are measured by their Scheduler
+	_, observed := engine.(scheduler.Observer)
+	go p.watch(ctx, key, frame.Name, e, !observed)
+	return meta.Name, nil
+}
+
+// watch logs output of the execution and notifies when it finishes. Duration
+// of the execution is observed from the start of the watch if measure is set.
+func (p *Player) watch(ctx context.Context, key types.NamespacedName, frameName string, e execution.Execution, measure bool) {
+	start := time.Now()
+	buffer := bufio.NewReaderSize(e.Logs(), 32*1024)
+	for {
+		line, _, err := buffer.ReadLine()
+		if err != nil {
+			break
+		}
+		log.Infof("Task %s: %s", frameName, line)
+	}
+	if _, err := e.Wait(ctx); err != nil {
+		return
+	}
+	if measure {
+		metrics.FrameDuration.WithLabelValues(frameName).Observe(time.Since(start).Seconds())
+	}
+	if p.Notify != nil {
+		p.Notify(key)
+	}
+}
+
+// recordFrameResult records the result of the finished execution of the frame.
+// Duration of the execution is observed if the Scheduler knows when it ran.
+func (p *Player) recordFrameResult(ctx context.Context, play *corev1alpha1.Play, frame *corev1alpha1.Frame, observation execution.Observation) error {
+	engine, err := p.schedulers.Get(frame.Runtime)
+	if err != nil {
+		return err
+	}
+	status := observation.Status
+	var failure *corev1alpha1.FrameFailure
+	if status.Reason != "" {
+		failure = &corev1alpha1.FrameFailure{Reason: status.Reason, Message: status.Message}
+	}
+	if err := engine.UpdateFrameResult(ctx, *play, frame.ID, status.ExitCode, failure); err != nil {
+		return fmt.Errorf("Updating frame result failed: %s", err)
+	}
+	if play.Status.Frames == nil {
+		play.Status.Frames = make(map[string]int)
+	}
+	play.Status.Frames[frame.ID] = status.ExitCode
+	if !observation.StartTime.IsZero() && !observation.CompletionTime.IsZero() {
+		metrics.FrameDuration.WithLabelValues(frame.Name).Observe(observation.CompletionTime.Sub(observation.StartTime).Seconds())
+	}
+	p.endFrameSpan(play, frame.ID, status.ExitCode, failure)
+	p.recordHistory(play, func(h *history.Store) error {
+		return h.RecordFrameFinished(play, frame.ID, status.ExitCode, failure, time.Now())
+	})
+	if failure == nil {
+		p.event(play, corev1.EventTypeNormal, ReasonFrameFinished, "Frame %s finished", frame.Name)
+		return nil
+	}
+	if play.Status.FrameFailures == nil {
+		play.Status.FrameFailures = make(map[string]corev1alpha1.FrameFailure)
+	}
+	play.Status.FrameFailures[frame.ID] = *failure
+	p.event(play, corev1.EventTypeWarning, ReasonFrameFailed, "Frame %s failed with %s: %s", frame.Name, failure.Reason, failure.Message)
+	return nil
+}
+
+// finish records the final phase of the Play and drops its executions
+func (p *Player) finish(ctx context.Context, engine scheduler.Scheduler, play *corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) error {
+	if err := engine.UpdatePlayPhase(ctx, *play, phase); err != nil {
+		return err
+	}
+	play.Status.Phase = phase
+	p.endPlaySpan(play, phase)
+	p.recordHistory(play, func(h *history.Store) error {
+		return h.RecordPlayFinished(play, phase, time.Now())
+	})
+	if eventType, reason, ok := PhaseEvent(phase); ok {
+		p.event(play, eventType, reason, "Play entered phase %s", phase)
+	}
+	p.Forget(types.NamespacedName{Namespace: play.Namespace, Name: play.Name})
+	return nil
+}
+
+// Cancel stops executions of the Play and finishes the Play in phase Failed.
+// Executions of Schedulers implementing scheduler.Observer are cancelled by
+// labels of the Play, so executions started by other replicas stop as well.
+func (p *Player) Cancel(ctx context.Context, play *corev1alpha1.Play) error {
+	engine, err := p.schedulers.Get("")
+	if err != nil {
+		return err
+	}
+	if mainPlay := mainScreenplay(play); mainPlay != nil {
+		cancelled := make(map[string]bool)
+		for _, scene := range mainPlay.Scenes {
+			for _, frame := range scene.Frames {
+				if cancelled[frame.Runtime] {
+					continue
+				}
+				cancelled[frame.Runtime] = true
+				frameEngine, err := p.schedulers.Get(frame.Runtime)
+				if err != nil {
+					continue
+				}
+				if observer, ok := frameEngine.(scheduler.Observer); ok {
+					if err := observer.CancelAll(ctx, play.Namespace, PlaySelector(play)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	key := types.NamespacedName{Namespace: play.Namespace, Name: play.Name}
+	p.lock.Lock()
+	var running []execution.Execution
+	for _, e := range p.executions[key] {
+		if !e.Status().Finished {
+			running = append(running, e)
+		}
+	}
+	p.lock.Unlock()
+	for _, e := range running {
+		if err := e.Cancel(); err != nil {
+			log.Errorf("Failed to cancel execution of %s: %s", play.Name, err)
+		}
+	}
+	p.event(play, corev1.EventTypeWarning, ReasonPlayCancelled, "Play was cancelled")
+	return p.finish(ctx, engine, play, corev1alpha1.PlayFailed)
+}
+
+// recordHistory records history of the Play if the Player has a history store.
+// Plays don't fail when their history can't be recorded.
+func (p *Player) recordHistory(play *corev1alpha1.Play, record func(*history.Store) error) {
+	if p.History == nil {
+		return
+	}
+	if err := record(p.History); err != nil {
+		log.Warnf("Failed to record history of %s: %s", play.Name, err)
+	}
+}
+
+func (p *Player) execution(key types.NamespacedName, frameID string) execution.Execution {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.executions[key][frameID]
+}
+
+func (p *Player) track(key types.NamespacedName, frameID string, e execution.Execution) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.executions[key] == nil {
+		p.executions[key] = make(map[string]execution.Execution)
+	}
+	p.executions[key][frameID] = e
+}
+
+// Forget drops executions of the Play and ends its spans. Results of executions
+// which are still running are recorded only if they are recovered by the next
+// reconciliation.
+func (p *Player) Forget(key types.NamespacedName) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.executions, key)
+	delete(p.failures, key)
+	p.endTrace(key)
+}
+
+func mainScreenplay(play *corev1alpha1.Play) *corev1alpha1.Screenplay {
+	for i := range play.Spec.Screenplays {
+		if play.Spec.Screenplays[i].Name == mainScreenplayName {
+			return &play.Spec.Screenplays[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// apiClient is a minimal client of the Docker Engine API, which is served by
+// Docker and Podman sockets.
+type apiClient struct {
+	http    *http.Client
+	baseURL string
+}
+
+func newAPIClient(host string) (*apiClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	c := &apiClient{http: &http.Client{}}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		c.baseURL = "http://localhost"
+	case "tcp":
+		c.baseURL = fmt.Sprintf("http://%s", u.Host)
+	case "http", "https":
+		c.baseURL = strings.TrimSuffix(host, "/")
+	default:
+		return nil, fmt.Errorf("Unsupported container host: %s", host)
+	}
+	return c, nil
+}
+
+type containerConfig struct {
+	Image      string            `json:"Image"`
+	Entrypoint []string          `json:"Entrypoint,omitempty"`
+	Cmd        []string          `json:"Cmd,omitempty"`
+	Env        []string          `json:"Env,omitempty"`
+	WorkingDir string            `json:"WorkingDir,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+	HostConfig hostConfig        `json:"HostConfig"`
+}
+
+type hostConfig struct {
+	Binds []string `json:"Binds,omitempty"`
+}
+
+type apiError struct {
+	status  int
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Container API responded with %d: %s", e.status, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.status == http.StatusNotFound
+}
+
+// do sends a request and returns the body of a successful response
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, body interface{}) (io.ReadCloser, error) {
+	var reader io.Reader
+	if body != nil {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(bodyJSON)
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		apiErr := &apiError{status: resp.StatusCode}
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(respBody, apiErr) != nil {
+			apiErr.Message = string(respBody)
+		}
+		return nil, apiErr
+	}
+	return resp.Body, nil
+}
+
+// call sends a request and decodes the JSON response into out, if it's set
+func (c *apiClient) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	if out == nil {
+		_, err = io.Copy(ioutil.Discard, resp)
+		return err
+	}
+	return json.NewDecoder(resp).Decode(out)
+}
+
+func (c *apiClient) imageExists(ctx context.Context, image string) (bool, error) {
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/images/%s/json", image), nil, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pullImage pulls the image. Progress of the pull is streamed as JSON messages
+// and a pull which fails after it started is reported by an error message.
+func (c *apiClient) pullImage(ctx context.Context, image string) error {
+	query := url.Values{"fromImage": {image}}
+	if !hasTagOrDigest(image) {
+		// All tags of the image are pulled if it isn't set
+		query.Set("tag", "latest")
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/images/create", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	decoder := json.NewDecoder(resp)
+	for {
+		message := struct {
+			Error string `json:"error"`
+		}{}
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if message.Error != "" {
+			return fmt.Errorf("Failed to pull image %s: %s", image, message.Error)
+		}
+	}
+}
+
+// hasTagOrDigest checks if the image reference has a tag or a digest. Host of
+// the registry can have a port, so only the last path segment can have a tag.
+func hasTagOrDigest(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+	return strings.Contains(image[strings.LastIndex(image, "/")+1:], ":")
+}
+
+func (c *apiClient) createContainer(ctx context.Context, name string, config *containerConfig) (string, error) {
+	created := struct {
+		ID string `json:"Id"`
+	}{}
+	err := c.call(ctx, http.MethodPost, "/containers/create", url.Values{"name": {name}}, config, &created)
+	return created.ID, err
+}
+
+func (c *apiClient) startContainer(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/start", id), nil, nil, nil)
+}
+
+func (c *apiClient) removeContainer(ctx context.Context, id string) error {
+	err := c.call(ctx, http.MethodDelete, fmt.Sprintf("/containers/%s", id), url.Values{"force": {"1"}}, nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *apiClient) waitContainer(ctx context.Context, id string) (int, error) {
+	result := struct {
+		StatusCode int `json:"StatusCode"`
+	}{}
+	err := c.call(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/wait", id), nil, nil, &result)
+	return result.StatusCode, err
+}
+
+// followLogs copies output of the container to the writer until the container stops
+func (c *apiClient) followLogs(ctx context.Context, id string, w io.Writer) error {
+	query := url.Values{"follow": {"1"}, "stdout": {"1"}, "stderr": {"1"}}
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/logs", id), query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	return demultiplex(w, resp)
+}
+
+// demultiplex copies a multiplexed stdout and stderr stream of a container
+// without a TTY. Each frame has a header with the stream type and size.
+func demultiplex(w io.Writer, r io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/shell"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultHost is the socket of the local Docker daemon. Podman serves the same API
+	// on unix:///run/podman/podman.sock.
+	DefaultHost = "unix:///var/run/docker.sock"
+	// exit code reported when a container can't be started
+	startFailedExitCode = 127
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// ContainerRuntime defines a Scheduler which runs images of frames as containers
+// on the local machine. Volumes, vars and results of Plays are handled the same
+// way as in the Shell scheduler, with volumes bind mounted into the containers.
+type ContainerRuntime struct {
+	*shell.Shell
+	api *apiClient
+}
+
+// NewContainerRuntime creates a new ContainerRuntime using the container API
+// served on the host. Executions are kept in the directory.
+func NewContainerRuntime(host, dir string, c client.Client) (*ContainerRuntime, error) {
+	if host == "" {
+		host = DefaultHost
+	}
+	api, err := newAPIClient(host)
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerRuntime{
+		Shell: shell.NewShell(dir, c),
+		api:   api,
+	}, nil
+}
+
+// Run runs the containers of the Exec. Init containers are run sequentially
+// before all other containers, which run in parallel. Containers are removed when
+// the execution is cancelled or the context is done.
+func (r *ContainerRuntime) Run(ctx context.Context, meta metav1.ObjectMeta, e corev1alpha1.Exec) (execution.Execution, error) {
+	name := meta.Name
+	ns := meta.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	workDir, volumes, err := r.Volumes(ns, name, e.Template.Spec.Volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	var initContainers, containers []*instance
+	for i, c := range e.Template.Spec.InitContainers {
+		instance, err := r.instance(ns, fmt.Sprintf("%s-init-%d", name, i), meta.Labels, workDir, volumes, c)
+		if err != nil {
+			return nil, err
+		}
+		initContainers = append(initContainers, instance)
+	}
+	for i, c := range e.Template.Spec.Containers {
+		instance, err := r.instance(ns, fmt.Sprintf("%s-%d", name, i), meta.Labels, workDir, volumes, c)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, instance)
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("Exec %s doesn't have any containers", name)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	reader, writer := io.Pipe()
+	handle := execution.NewHandle(reader, func() error {
+		cancel()
+		for _, c := range append(initContainers, containers...) {
+			if err := r.api.removeContainer(context.Background(), c.name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	go func() {
+		defer cancel()
+		exit := 0
+		for _, c := range initContainers {
+			if exit = r.run(ctx, writer, c); exit != 0 {
+				break
+			}
+		}
+		if exit == 0 {
+			exits := make(chan int)
+			for _, c := range containers {
+				go func(c *instance) {
+					exits <- r.run(ctx, writer, c)
+				}(c)
+			}
+			for range containers {
+				if code := <-exits; exit == 0 {
+					exit = code
+				}
+			}
+		}
+		writer.Close()
+		handle.Finish(exit)
+	}()
+
+	return handle, nil
+}
+
+// instance is a container of an execution
+type instance struct {
+	name            string
+	imagePullPolicy corev1.PullPolicy
+	config          *containerConfig
+}
+
+// instance translates the container to the configuration of the container API
+func (r *ContainerRuntime) instance(namespace, name string, labels map[string]string, workDir string, volumes map[string]string, c corev1.Container) (*instance, error) {
+	if c.Image == "" {
+		return nil, fmt.Errorf("Container %s doesn't have an image", c.Name)
+	}
+	env, err := r.Env(namespace, c)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &containerConfig{
+		Image:      c.Image,
+		WorkingDir: c.WorkingDir,
+		Labels: map[string]string{
+			"runner": "kuberik",
+		},
+	}
+	for k, v := range labels {
+		config.Labels[k] = v
+	}
+	for _, command := range c.Command {
+		config.Entrypoint = append(config.Entrypoint, shell.ExpandEnv(command, env))
+	}
+	for _, arg := range c.Args {
+		config.Cmd = append(config.Cmd, shell.ExpandEnv(arg, env))
+	}
+	for _, e := range env {
+		config.Env = append(config.Env, fmt.Sprintf("%s=%s", e.Name, e.Value))
+	}
+	for _, m := range c.VolumeMounts {
+		dir, ok := volumes[m.Name]
+		if !ok {
+			return nil, fmt.Errorf("Volume %s mounted by container %s not found", m.Name, c.Name)
+		}
+		if m.SubPath != "" {
+			dir = filepath.Join(dir, m.SubPath)
+		}
+		bind := fmt.Sprintf("%s:%s", dir, m.MountPath)
+		if m.ReadOnly {
+			bind += ":ro"
+		}
+		config.HostConfig.Binds = append(config.HostConfig.Binds, bind)
+	}
+	if config.WorkingDir == "" {
+		config.WorkingDir = "/kuberik/workdir"
+		config.HostConfig.Binds = append(config.HostConfig.Binds, fmt.Sprintf("%s:%s", workDir, config.WorkingDir))
+	}
+
+	return &instance{
+		name:            strings.Trim(invalidNameChars.ReplaceAllString(fmt.Sprintf("kuberik-%s", name), "-"), "-"),
+		imagePullPolicy: c.ImagePullPolicy,
+		config:          config,
+	}, nil
+}
+
+// run runs the container to completion and returns its exit code
+func (r *ContainerRuntime) run(ctx context.Context, w io.Writer, e *instance) int {
+	if err := r.pull(ctx, e); err != nil {
+		fmt.Fprintf(w, "Failed to pull image %s: %s\n", e.config.Image, err)
+		return startFailedExitCode
+	}
+
+	// Container left behind by a previous execution is replaced
+	if err := r.api.removeContainer(ctx, e.name); err != nil {
+		log.Warnf("Failed to remove container %s: %s", e.name, err)
+	}
+	id, err := r.api.createContainer(ctx, e.name, e.config)
+	if err != nil {
+		fmt.Fprintf(w, "Failed to create container %s: %s\n", e.name, err)
+		return startFailedExitCode
+	}
+	defer func() {
+		// Container is removed even if the context is done, so it doesn't outlive the execution
+		if err := r.api.removeContainer(context.Background(), id); err != nil {
+			log.Warnf("Failed to remove container %s: %s", e.name, err)
+		}
+	}()
+	if err := r.api.startContainer(ctx, id); err != nil {
+		fmt.Fprintf(w, "Failed to start container %s: %s\n", e.name, err)
+		return startFailedExitCode
+	}
+	if err := r.api.followLogs(ctx, id, w); err != nil {
+		log.Warnf("Failed to follow logs of container %s: %s", e.name, err)
+	}
+	exit, err := r.api.waitContainer(ctx, id)
+	if err != nil {
+		log.Errorf("Failed to wait for container %s: %s", e.name, err)
+		return 1
+	}
+	return exit
+}
+
+func (r *ContainerRuntime) pull(ctx context.Context, e *instance) error {
+	if e.imagePullPolicy != corev1.PullAlways {
+		exists, err := r.api.imageExists(ctx, e.config.Image)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		if e.imagePullPolicy == corev1.PullNever {
+			return fmt.Errorf("Image %s not present and pull policy is %s", e.config.Image, e.imagePullPolicy)
+		}
+	}
+	return r.api.pullImage(ctx, e.config.Image)
+}
@@ -0,0 +1,147 @@
+package container
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeAPI serves the subset of the container API used by the scheduler
+func fakeAPI(t *testing.T, created *containerConfig) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/images/"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "no such image"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/images/create":
+			if tag := r.URL.Query().Get("tag"); tag != "latest" {
+				t.Errorf("Expected image to be pulled with tag latest, got %q", tag)
+			}
+			w.Write([]byte(`{"status": "Pulling from library/alpine"}` + "\n" + `{"status": "Downloaded"}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/containers/create":
+			if err := json.NewDecoder(r.Body).Decode(created); err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id": "abc"}`))
+		case r.URL.Path == "/containers/abc/start":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/containers/abc/logs":
+			for _, line := range []string{"hello\n", "world\n"} {
+				header := make([]byte, 8)
+				header[0] = 1
+				binary.BigEndian.PutUint32(header[4:], uint32(len(line)))
+				w.Write(append(header, line...))
+			}
+		case r.URL.Path == "/containers/abc/wait":
+			w.Write([]byte(`{"StatusCode": 2}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberik-container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	created := &containerConfig{}
+	server := fakeAPI(t, created)
+	defer server.Close()
+
+	r, err := NewContainerRuntime(server.URL, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	execution, err := r.Run(context.Background(), metav1.ObjectMeta{Name: "hello", Labels: map[string]string{"core.kuberik.io/play": "hello"}}, corev1alpha1.Exec{
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image:   "alpine",
+					Command: []string{"echo"},
+					Args:    []string{"$(GREETING)"},
+					Env:     []corev1.EnvVar{{Name: "GREETING", Value: "hello"}},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "cache", MountPath: "/cache"},
+					},
+				}},
+				Volumes: []corev1.Volume{
+					{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs, _ := ioutil.ReadAll(execution.Logs())
+	exit, err := execution.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exit != 2 {
+		t.Errorf("Expected exit code 2, got %d", exit)
+	}
+	if string(logs) != "hello\nworld\n" {
+		t.Errorf("Unexpected logs: %q", logs)
+	}
+
+	if created.Image != "alpine" || len(created.Cmd) != 1 || created.Cmd[0] != "hello" {
+		t.Errorf("Container created with wrong image or args: %+v", created)
+	}
+	if created.Labels["core.kuberik.io/play"] != "hello" {
+		t.Errorf("Container created without labels of the execution: %v", created.Labels)
+	}
+	if len(created.Env) != 1 || created.Env[0] != "GREETING=hello" {
+		t.Errorf("Container created with wrong env: %v", created.Env)
+	}
+	if len(created.HostConfig.Binds) != 2 || !strings.HasSuffix(created.HostConfig.Binds[0], ":/cache") {
+		t.Errorf("Container created with wrong binds: %v", created.HostConfig.Binds)
+	}
+}
+
+func TestPullImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Failures after the pull started are reported in the stream with a successful status
+		w.Write([]byte(`{"status": "Pulling from library/missing"}` + "\n" + `{"error": "manifest unknown"}`))
+	}))
+	defer server.Close()
+
+	c, err := newAPIClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.pullImage(context.Background(), "missing:1.0"); err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("Expected pull to fail with error from the stream, got %v", err)
+	}
+}
+
+func TestHasTagOrDigest(t *testing.T) {
+	for image, expected := range map[string]bool{
+		"alpine":                        false,
+		"alpine:3.11":                   true,
+		"registry:5000/alpine":          false,
+		"registry:5000/alpine:3.11":     true,
+		"alpine@sha256:0123456789abcde": true,
+	} {
+		if hasTagOrDigest(image) != expected {
+			t.Errorf("Expected image %s to have a tag or digest: %t", image, expected)
+		}
+	}
+}
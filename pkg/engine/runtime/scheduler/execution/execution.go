@@ -0,0 +1,134 @@
+// Package execution defines handles of executions started by Schedulers.
+package execution
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Execution is a handle of an execution started by a Scheduler
+type Execution interface {
+	// Logs returns the output of the execution. It's closed when the execution finishes.
+	Logs() io.Reader
+	// Wait blocks until the execution finishes and returns its exit code. It
+	// returns an error if the context is done before the execution finishes.
+	Wait(ctx context.Context) (int, error)
+	// Cancel stops the execution
+	Cancel() error
+	// Status returns the current status of the execution without blocking
+	Status() Status
+}
+
+// Status is the status of an execution
+type Status struct {
+	// Finished is true once the execution has finished
+	Finished bool
+	// ExitCode is the exit code of a finished execution
+	ExitCode int
+	// Reason is a brief CamelCase cause of a failed execution, if the Scheduler diagnosed it
+	Reason string
+	// Message describes the failure of the execution
+	Message string
+}
+
+// Observation is the state of an execution observed by a Scheduler, independent
+// of handles of the execution
+type Observation struct {
+	Status
+	// Failed is the number of failed attempts of the execution. Execution
+	// which failed while it's still running is retried.
+	Failed int
+	// StartTime and CompletionTime are the times when the execution started
+	// and finished. They are zero if the Scheduler doesn't know them.
+	StartTime      time.Time
+	CompletionTime time.Time
+}
+
+// Causes of failed executions diagnosed by Schedulers
+const (
+	// ReasonError means the application exited with a non-zero exit code
+	ReasonError = "Error"
+	// ReasonImagePullBackOff means an image of the execution couldn't be pulled
+	ReasonImagePullBackOff = "ImagePullBackOff"
+	// ReasonOOMKilled means a container was killed because it ran out of memory
+	ReasonOOMKilled = "OOMKilled"
+	// ReasonEvicted means the execution was evicted from its node
+	ReasonEvicted = "Evicted"
+	// ReasonDeadlineExceeded means the execution ran longer than its deadline
+	ReasonDeadlineExceeded = "DeadlineExceeded"
+	// ReasonUnschedulable means the execution couldn't be scheduled on any node
+	ReasonUnschedulable = "Unschedulable"
+)
+
+// Handle implements Execution for Schedulers. Scheduler reports the end of the
+// execution with Finish.
+type Handle struct {
+	logs   io.Reader
+	cancel func() error
+
+	lock   sync.Mutex
+	done   chan struct{}
+	status Status
+}
+
+// NewHandle creates a Handle of a running execution. Cancel is called to stop the execution.
+func NewHandle(logs io.Reader, cancel func() error) *Handle {
+	return &Handle{
+		logs:   logs,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Finish records the exit code of the execution and releases all waiting callers.
+// Only the first call of Finish or Fail has an effect.
+func (h *Handle) Finish(exitCode int) {
+	h.finish(Status{Finished: true, ExitCode: exitCode})
+}
+
+// Fail records the exit code of a failed execution with the diagnosed cause of the failure
+func (h *Handle) Fail(exitCode int, reason, message string) {
+	h.finish(Status{Finished: true, ExitCode: exitCode, Reason: reason, Message: message})
+}
+
+func (h *Handle) finish(status Status) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.status.Finished {
+		return
+	}
+	h.status = status
+	close(h.done)
+}
+
+// Logs returns the output of the execution
+func (h *Handle) Logs() io.Reader {
+	return h.logs
+}
+
+// Wait blocks until the execution finishes or the context is done
+func (h *Handle) Wait(ctx context.Context) (int, error) {
+	select {
+	case <-h.done:
+		return h.Status().ExitCode, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Cancel stops the execution
+func (h *Handle) Cancel() error {
+	if h.cancel == nil {
+		return nil
+	}
+	return h.cancel()
+}
+
+// Status returns the current status of the execution
+func (h *Handle) Status() Status {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.status
+}
@@ -0,0 +1,252 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/kubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CancelledExitCode is the exit code of cancelled executions
+const CancelledExitCode = 137
+
+// Script defines the outcome of an execution run by the fake Scheduler
+type Script struct {
+	// Exit is the exit code of the execution
+	Exit int
+	// Delay is the time after which the execution finishes
+	Delay time.Duration
+	// Output is written to the output of the execution
+	Output string
+	// Reason and Message describe the failure of the execution if Reason is set
+	Reason  string
+	Message string
+	// Retries is the number of attempts which fail before the outcome. They
+	// are spread evenly over the delay.
+	Retries int
+}
+
+// Run is an execution recorded by the fake Scheduler
+type Run struct {
+	metav1.ObjectMeta
+	Exec corev1alpha1.Exec
+
+	script  Script
+	started time.Time
+}
+
+// failed returns the number of failed attempts of the run
+func (r Run) failed(status execution.Status) int {
+	if status.Finished || r.script.Delay == 0 {
+		return r.script.Retries
+	}
+	failed := int(time.Since(r.started) * time.Duration(r.script.Retries+1) / r.script.Delay)
+	if failed > r.script.Retries {
+		return r.script.Retries
+	}
+	return failed
+}
+
+// Scheduler is an in-memory Scheduler which doesn't execute anything. Outcome of
+// executions is scripted by the image of their first container. Like Jobs on
+// Kubernetes, running an execution with a name which was already run recovers
+// the existing execution.
+type Scheduler struct {
+	// Client is used to update status of Plays if it's set
+	Client client.Client
+	// Default is the outcome of executions with images which weren't scripted
+	Default Script
+
+	lock     sync.Mutex
+	scripts  map[string]Script
+	runs     []Run
+	handles  map[string]*execution.Handle
+	finished map[string]time.Time
+	phases   map[string][]corev1alpha1.PlayPhaseType
+	results  map[string]map[string]int
+}
+
+// NewScheduler creates a fake Scheduler which updates Plays with the client.
+// Client can be nil.
+func NewScheduler(c client.Client) *Scheduler {
+	return &Scheduler{
+		Client:   c,
+		scripts:  make(map[string]Script),
+		handles:  make(map[string]*execution.Handle),
+		finished: make(map[string]time.Time),
+		phases:   make(map[string][]corev1alpha1.PlayPhaseType),
+		results:  make(map[string]map[string]int),
+	}
+}
+
+// Script sets the outcome of executions running the image
+func (s *Scheduler) Script(image string, script Script) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.scripts[image] = script
+}
+
+// Run records the execution and finishes it as scripted. Cancelled executions
+// finish right away with CancelledExitCode.
+func (s *Scheduler) Run(ctx context.Context, meta metav1.ObjectMeta, e corev1alpha1.Exec) (execution.Execution, error) {
+	if len(e.Template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("Exec %s doesn't have any containers", meta.Name)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := playKey(meta.Namespace, meta.Name)
+	if handle, ok := s.handles[key]; ok {
+		return handle, nil
+	}
+	script, ok := s.scripts[e.Template.Spec.Containers[0].Image]
+	if !ok {
+		script = s.Default
+	}
+	s.runs = append(s.runs, Run{ObjectMeta: *meta.DeepCopy(), Exec: *e.DeepCopy(), script: script, started: time.Now()})
+
+	var handle *execution.Handle
+	var timer *time.Timer
+	handle = execution.NewHandle(strings.NewReader(script.Output), func() error {
+		timer.Stop()
+		s.finish(key)
+		handle.Finish(CancelledExitCode)
+		return nil
+	})
+	timer = time.AfterFunc(script.Delay, func() {
+		s.finish(key)
+		if script.Reason != "" {
+			handle.Fail(script.Exit, script.Reason, script.Message)
+			return
+		}
+		handle.Finish(script.Exit)
+	})
+	s.handles[key] = handle
+	return handle, nil
+}
+
+// finish records the time when the execution finished
+func (s *Scheduler) finish(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.finished[key]; !ok {
+		s.finished[key] = time.Now()
+	}
+}
+
+// Observe returns executions in the namespace with the labels, keyed by their
+// names. Like Jobs on Kubernetes, executions are observed independently of
+// the Player which started them.
+func (s *Scheduler) Observe(ctx context.Context, namespace string, labels map[string]string) (map[string]execution.Observation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	observations := make(map[string]execution.Observation)
+	for _, run := range s.matching(namespace, labels) {
+		key := playKey(run.Namespace, run.Name)
+		status := s.handles[key].Status()
+		observations[run.Name] = execution.Observation{
+			Status:         status,
+			Failed:         run.failed(status),
+			StartTime:      run.started,
+			CompletionTime: s.finished[key],
+		}
+	}
+	return observations, nil
+}
+
+// CancelAll cancels executions in the namespace with the labels
+func (s *Scheduler) CancelAll(ctx context.Context, namespace string, labels map[string]string) error {
+	s.lock.Lock()
+	var handles []*execution.Handle
+	for _, run := range s.matching(namespace, labels) {
+		handles = append(handles, s.handles[playKey(run.Namespace, run.Name)])
+	}
+	s.lock.Unlock()
+	for _, handle := range handles {
+		handle.Cancel()
+	}
+	return nil
+}
+
+// matching returns runs in the namespace with the labels. Lock must be held.
+func (s *Scheduler) matching(namespace string, labels map[string]string) []Run {
+	var runs []Run
+	for _, run := range s.runs {
+		if run.Namespace != namespace {
+			continue
+		}
+		matches := true
+		for k, v := range labels {
+			matches = matches && run.Labels[k] == v
+		}
+		if matches {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// Runs returns all executions run by the Scheduler
+func (s *Scheduler) Runs() []Run {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Run{}, s.runs...)
+}
+
+// Phases returns all phases the Play was updated to, in order
+func (s *Scheduler) Phases(namespace, name string) []corev1alpha1.PlayPhaseType {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]corev1alpha1.PlayPhaseType{}, s.phases[playKey(namespace, name)]...)
+}
+
+// FrameResults returns results of frames of the Play keyed by frame ID
+func (s *Scheduler) FrameResults(namespace, name string) map[string]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	results := make(map[string]int)
+	for id, result := range s.results[playKey(namespace, name)] {
+		results[id] = result
+	}
+	return results
+}
+
+// UpdatePlayPhase records the phase of a Play
+func (s *Scheduler) UpdatePlayPhase(ctx context.Context, play corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) error {
+	s.lock.Lock()
+	key := playKey(play.Namespace, play.Name)
+	s.phases[key] = append(s.phases[key], phase)
+	s.lock.Unlock()
+
+	if s.Client == nil {
+		return nil
+	}
+	return kubernetes.PatchPlayPhase(ctx, s.Client, play, phase)
+}
+
+// UpdateFrameResult records the result of a Frame in the Play
+func (s *Scheduler) UpdateFrameResult(ctx context.Context, play corev1alpha1.Play, ID string, result int, failure *corev1alpha1.FrameFailure) error {
+	s.lock.Lock()
+	key := playKey(play.Namespace, play.Name)
+	if s.results[key] == nil {
+		s.results[key] = make(map[string]int)
+	}
+	s.results[key][ID] = result
+	s.lock.Unlock()
+
+	if s.Client == nil {
+		return nil
+	}
+	return kubernetes.PatchFrameResult(ctx, s.Client, play, ID, result, failure)
+}
+
+func playKey(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
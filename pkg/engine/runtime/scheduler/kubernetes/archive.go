@@ -0,0 +1,99 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kuberik/kuberik/pkg/engine/config"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LogArchiveLabel marks ConfigMaps holding archived logs of a Job. The
+	// ConfigMap is named after the Job and has the labels of the Job.
+	LogArchiveLabel = "core.kuberik.io/log-archive"
+	// maximum size of logs of a container in an archive, so logs of all
+	// containers fit into a ConfigMap
+	maxArchivedLogBytes = 128 * 1024
+)
+
+// LogArchiveKey returns the key of logs of the container of the Pod in a log archive
+func LogArchiveKey(pod, container string) string {
+	return fmt.Sprintf("%s.%s", pod, container)
+}
+
+// ParseLogArchiveKey returns the Pod and the container of the key of a log archive
+func ParseLogArchiveKey(key string) (pod, container string) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 {
+		return key, ""
+	}
+	return parts[0], parts[1]
+}
+
+// archiveLogs keeps the last lines of output of containers of the finished
+// Job in a ConfigMap, so the logs can be read once the Pods are gone. The
+// ConfigMap is owned by the owners of the Job, so it's deleted with the Play.
+func (r *KubernetesRuntime) archiveLogs(job *batchv1.Job, pods []corev1.Pod) {
+	if config.LogArchiveLines <= 0 {
+		return
+	}
+	lines := int64(config.LogArchiveLines)
+	logs := make(map[string]string)
+	for _, pod := range pods {
+		for _, status := range containerStatuses(pod) {
+			if status.State.Terminated == nil {
+				continue
+			}
+			output, err := r.kubernetesClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: status.Name,
+				TailLines: &lines,
+			}).DoRaw()
+			if err != nil {
+				log.Warnf("Failed to get logs of container %s of pod %s: %s", status.Name, pod.Name, err)
+				continue
+			}
+			logs[LogArchiveKey(pod.Name, status.Name)] = string(output)
+		}
+	}
+	if len(logs) == 0 {
+		return
+	}
+	_, err := r.kubernetesClient.CoreV1().ConfigMaps(job.Namespace).Create(newLogArchive(job, logs))
+	if err != nil && !errors.IsAlreadyExists(err) {
+		log.Errorf("Failed to archive logs of job %s: %s", job.Name, err)
+	}
+}
+
+// newLogArchive creates the ConfigMap archiving logs of the Job. Logs longer
+// than the limit are cut to their last lines.
+func newLogArchive(job *batchv1.Job, logs map[string]string) *corev1.ConfigMap {
+	labels := map[string]string{LogArchiveLabel: "true"}
+	for k, v := range job.Labels {
+		labels[k] = v
+	}
+	data := make(map[string]string)
+	for key, output := range logs {
+		if len(output) > maxArchivedLogBytes {
+			output = output[len(output)-maxArchivedLogBytes:]
+			if i := strings.IndexByte(output, '\n'); i >= 0 {
+				output = output[i+1:]
+			}
+		}
+		data[key] = output
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            job.Name,
+			Namespace:       job.Namespace,
+			Labels:          labels,
+			Annotations:     job.Annotations,
+			OwnerReferences: job.OwnerReferences,
+		},
+		Data: data,
+	}
+}
@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewLogArchive(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:      "job",
+		Namespace: "default",
+		Labels:    map[string]string{"core.kuberik.io/play": "play"},
+	}}
+	long := strings.Repeat("line\n", maxArchivedLogBytes/5+10)
+	archive := newLogArchive(job, map[string]string{
+		LogArchiveKey("job-abcde", "main"):    "hello\n",
+		LogArchiveKey("job-abcde", "sidecar"): long,
+	})
+
+	if archive.Name != "job" || archive.Labels[LogArchiveLabel] != "true" || archive.Labels["core.kuberik.io/play"] != "play" {
+		t.Errorf("Expected archive named and labeled after the job, got %+v", archive.ObjectMeta)
+	}
+	if output := archive.Data["job-abcde.main"]; output != "hello\n" {
+		t.Errorf("Expected logs of container main, got %q", output)
+	}
+	output := archive.Data["job-abcde.sidecar"]
+	if len(output) > maxArchivedLogBytes || !strings.HasPrefix(output, "line\n") || !strings.HasSuffix(output, "line\n") {
+		t.Errorf("Expected logs of container sidecar to be cut to whole last lines, got %d bytes", len(output))
+	}
+	if pod, container := ParseLogArchiveKey("job-abcde.sidecar"); pod != "job-abcde" || container != "sidecar" {
+		t.Errorf("Expected key of pod job-abcde and container sidecar, got %s and %s", pod, container)
+	}
+}
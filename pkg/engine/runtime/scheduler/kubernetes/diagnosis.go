@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// waiting reasons of containers whose image can't be pulled
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// failure is the diagnosed cause of a failed Job
+type failure struct {
+	exitCode int
+	reason   string
+	message  string
+}
+
+// diagnoseFailure classifies the failure of a failed Job from its conditions
+// and Pods. Application errors are reported with the exit code of the container.
+func diagnoseFailure(job *batchv1.Job, pods []corev1.Pod) failure {
+	result := failure{exitCode: 1, reason: execution.ReasonError, message: "Job failed"}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type != batchv1.JobFailed {
+			continue
+		}
+		if condition.Reason == execution.ReasonDeadlineExceeded {
+			return failure{exitCode: 1, reason: execution.ReasonDeadlineExceeded, message: condition.Message}
+		}
+		if condition.Message != "" {
+			result.message = condition.Message
+		}
+	}
+
+	applicationError := false
+	for _, pod := range pods {
+		if pod.Status.Reason == execution.ReasonEvicted {
+			return failure{exitCode: 1, reason: execution.ReasonEvicted, message: pod.Status.Message}
+		}
+		for _, status := range containerStatuses(pod) {
+			if waiting := status.State.Waiting; waiting != nil && imagePullReasons[waiting.Reason] {
+				return imagePullFailure(status)
+			}
+			terminated := status.State.Terminated
+			if terminated == nil || terminated.ExitCode == 0 {
+				continue
+			}
+			if terminated.Reason == execution.ReasonOOMKilled {
+				return failure{
+					exitCode: int(terminated.ExitCode),
+					reason:   execution.ReasonOOMKilled,
+					message:  fmt.Sprintf("Container %s ran out of memory", status.Name),
+				}
+			}
+			if !applicationError {
+				applicationError = true
+				result.exitCode = int(terminated.ExitCode)
+				result.message = fmt.Sprintf("Container %s exited with code %d", status.Name, terminated.ExitCode)
+				if terminated.Message != "" {
+					result.message += ": " + terminated.Message
+				}
+			}
+		}
+	}
+	return result
+}
+
+// diagnoseStuck returns the cause why a Pod of a running Job hasn't started for
+// longer than the grace period, or nil if none of the Pods is stuck
+func diagnoseStuck(pods []corev1.Pod, now time.Time, grace time.Duration) *failure {
+	for _, pod := range pods {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+				condition.Reason == corev1.PodReasonUnschedulable && now.Sub(condition.LastTransitionTime.Time) > grace {
+				return &failure{exitCode: 1, reason: execution.ReasonUnschedulable, message: condition.Message}
+			}
+		}
+		if now.Sub(pod.CreationTimestamp.Time) <= grace {
+			continue
+		}
+		for _, status := range containerStatuses(pod) {
+			if waiting := status.State.Waiting; waiting != nil && imagePullReasons[waiting.Reason] {
+				f := imagePullFailure(status)
+				return &f
+			}
+		}
+	}
+	return nil
+}
+
+func imagePullFailure(status corev1.ContainerStatus) failure {
+	message := fmt.Sprintf("Image %s of container %s can't be pulled", status.Image, status.Name)
+	if status.State.Waiting.Message != "" {
+		message += ": " + status.State.Waiting.Message
+	}
+	return failure{exitCode: 1, reason: execution.ReasonImagePullBackOff, message: message}
+}
+
+func containerStatuses(pod corev1.Pod) []corev1.ContainerStatus {
+	return append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+}
@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func terminatedPod(reason string, exitCode int32) corev1.Pod {
+	return corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+		Name:  "main",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode}},
+	}}}}
+}
+
+func waitingPod(reason string, created time.Time) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "main",
+			Image: "missing",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}},
+		}}},
+	}
+}
+
+func unschedulablePod(since time.Time) corev1.Pod {
+	return corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+		Type:               corev1.PodScheduled,
+		Status:             corev1.ConditionFalse,
+		Reason:             corev1.PodReasonUnschedulable,
+		LastTransitionTime: metav1.NewTime(since),
+	}}}}
+}
+
+func TestDiagnoseFailure(t *testing.T) {
+	failed := &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit",
+	}}}}
+	deadline := &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Reason: "DeadlineExceeded", Message: "Job was active longer than specified deadline",
+	}}}}
+	evicted := corev1.Pod{Status: corev1.PodStatus{Reason: "Evicted", Message: "The node was low on resource: memory."}}
+
+	for _, test := range []struct {
+		name     string
+		job      *batchv1.Job
+		pods     []corev1.Pod
+		exitCode int
+		reason   string
+	}{
+		{"application error", failed, []corev1.Pod{terminatedPod("Error", 3)}, 3, execution.ReasonError},
+		{"oom killed", failed, []corev1.Pod{terminatedPod("Error", 1), terminatedPod("OOMKilled", 137)}, 137, execution.ReasonOOMKilled},
+		{"evicted", failed, []corev1.Pod{evicted}, 1, execution.ReasonEvicted},
+		{"deadline exceeded", deadline, []corev1.Pod{terminatedPod("Error", 137)}, 1, execution.ReasonDeadlineExceeded},
+		{"image pull", failed, []corev1.Pod{waitingPod("ImagePullBackOff", time.Now())}, 1, execution.ReasonImagePullBackOff},
+		{"no pods", failed, nil, 1, execution.ReasonError},
+	} {
+		f := diagnoseFailure(test.job, test.pods)
+		if f.exitCode != test.exitCode || f.reason != test.reason || f.message == "" {
+			t.Errorf("%s: expected %s with exit code %d, got %+v", test.name, test.reason, test.exitCode, f)
+		}
+	}
+}
+
+func TestDiagnoseStuck(t *testing.T) {
+	now := time.Now()
+	grace := time.Minute
+	for _, test := range []struct {
+		name   string
+		pods   []corev1.Pod
+		reason string
+	}{
+		{"image pull", []corev1.Pod{waitingPod("ErrImagePull", now.Add(-2*grace))}, execution.ReasonImagePullBackOff},
+		{"image pull in grace period", []corev1.Pod{waitingPod("ImagePullBackOff", now.Add(-grace/2))}, ""},
+		{"creating container", []corev1.Pod{waitingPod("ContainerCreating", now.Add(-2*grace))}, ""},
+		{"unschedulable", []corev1.Pod{unschedulablePod(now.Add(-2 * grace))}, execution.ReasonUnschedulable},
+		{"unschedulable in grace period", []corev1.Pod{unschedulablePod(now)}, ""},
+	} {
+		f := diagnoseStuck(test.pods, now, grace)
+		if test.reason == "" {
+			if f != nil {
+				t.Errorf("%s: expected Pods not to be stuck, got %+v", test.name, *f)
+			}
+			continue
+		}
+		if f == nil || f.reason != test.reason {
+			t.Errorf("%s: expected Pods to be stuck with %s, got %+v", test.name, test.reason, f)
+		}
+	}
+}
@@ -2,18 +2,29 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"sync"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
 	"github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/metrics"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	"github.com/kuberik/kuberik/pkg/engine/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 
@@ -24,14 +35,21 @@ import (
 const (
 	// maximum length of job name
 	maxJobNameLength = 63
-)
+	// interval of checks whether Pods of a running Job are stuck
+	stuckCheckInterval = 10 * time.Second
 
-var updateLock sync.Mutex
+	// failureReasonAnnotation holds the cause of the failure of a Job which was
+	// stopped by Kuberik, since such Jobs don't fail on their own
+	failureReasonAnnotation = "core.kuberik.io/failure-reason"
+	// failureMessageAnnotation describes the failure of a Job stopped by Kuberik
+	failureMessageAnnotation = "core.kuberik.io/failure-message"
+)
 
 // KubernetesRuntime defines a Scheduler which executes Plays on Kubernetes
 type KubernetesRuntime struct {
 	config           *rest.Config
-	kubernetesClient *kubernetes.Clientset
+	kubernetesClient kubernetes.Interface
+	recorder         record.EventRecorder
 	// kuberikClient    *clientv1alpha1.CoreV1alpha1Client
 }
 
@@ -40,34 +58,59 @@ func NewKubernetesRuntime(c *rest.Config) *KubernetesRuntime {
 	kubernetesClient, _ := kubernetes.NewForConfig(c)
 	// kuberikClient, _ := clientv1alpha1.NewForConfig(c)
 
+	// Events are emitted with the recorder of the engine, or directly when running without the operator
+	recorder := config.Recorder
+	if recorder == nil && kubernetesClient != nil {
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubernetesClient.CoreV1().Events("")})
+		recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "kuberik"})
+	}
+
 	return &KubernetesRuntime{
 		config:           c,
 		kubernetesClient: kubernetesClient,
+		recorder:         recorder,
 		// kuberikClient:    kuberikClient,
 	}
 }
 
-// Run creates an execution on Kubernetes
-func (r *KubernetesRuntime) Run(name string, namespace corev1.Namespace, e corev1alpha1.Exec) (io.Reader, chan int, error) {
-	if len(name) > maxJobNameLength {
-		name = name[:maxJobNameLength]
-	}
+// Run creates an execution on Kubernetes. The Job is watched until it finishes
+// or the context is done, and it's deleted when the execution is cancelled.
+func (r *KubernetesRuntime) Run(ctx context.Context, meta metav1.ObjectMeta, e corev1alpha1.Exec) (execution.Execution, error) {
+	meta.Name = JobName(meta.Name)
 	reader, writer := io.Pipe()
-	result := make(chan int)
 
-	jobDefinition := newRunJob(name, &e)
+	jobDefinition := newRunJob(meta, &e)
+	_, span := tracing.Tracer().Start(ctx, "CreateJob", trace.WithAttributes(attribute.String("kuberik.job", jobDefinition.Name)))
 	// Try to recover first
-	jobInstance, err := r.kubernetesClient.BatchV1().Jobs(namespace.Name).Get(jobDefinition.GetName(), metav1.GetOptions{})
+	jobInstance, err := r.kubernetesClient.BatchV1().Jobs(meta.Namespace).Get(jobDefinition.GetName(), metav1.GetOptions{})
 	if errors.IsNotFound(err) {
-		jobInstance, err = r.kubernetesClient.BatchV1().Jobs(namespace.Name).Create(newRunJob(name, &e))
+		jobInstance, err = r.kubernetesClient.BatchV1().Jobs(meta.Namespace).Create(jobDefinition)
+	} else if err == nil {
+		span.SetAttributes(attribute.Bool("kuberik.recovered", true))
 	}
 	if err != nil {
-		return nil, nil, err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
 	}
+	span.End()
 
-	go r.watchJob(writer, result, jobInstance)
+	handle := execution.NewHandle(reader, func() error {
+		return r.deleteJob(jobInstance)
+	})
+	go r.watchJob(ctx, writer, handle, jobInstance)
 
-	return reader, result, nil
+	return handle, nil
+}
+
+// JobName returns the name of the Job created for an execution with the given name
+func JobName(name string) string {
+	if len(name) > maxJobNameLength {
+		return name[:maxJobNameLength]
+	}
+	return name
 }
 
 var (
@@ -75,10 +118,19 @@ var (
 	zero     int32 = 0
 )
 
-func newRunJob(name string, e *corev1alpha1.Exec) *batchv1.Job {
+// newRunJob creates a Job of the execution with labels and owners of the metadata
+func newRunJob(meta metav1.ObjectMeta, e *corev1alpha1.Exec) *batchv1.Job {
 	labels := map[string]string{
 		"runner": "kuberik",
 	}
+	for k, v := range meta.Labels {
+		labels[k] = v
+		// Pods are labeled as well, so they can be found by the Play
+		if e.Template.Labels == nil {
+			e.Template.Labels = make(map[string]string)
+		}
+		e.Template.Labels[k] = v
+	}
 	if e.BackoffLimit == nil {
 		e.BackoffLimit = &zero
 	}
@@ -86,13 +138,16 @@ func newRunJob(name string, e *corev1alpha1.Exec) *batchv1.Job {
 		e.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
 	}
 	if len(e.Template.Spec.Containers) == 1 {
-		e.Template.Spec.Containers[0].Name = name
+		e.Template.Spec.Containers[0].Name = meta.Name
 	}
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   name,
-			Labels: labels,
+			Name:            meta.Name,
+			Namespace:       meta.Namespace,
+			Labels:          labels,
+			Annotations:     meta.Annotations,
+			OwnerReferences: meta.OwnerReferences,
 		},
 		Spec: *e,
 	}
@@ -100,85 +155,284 @@ func newRunJob(name string, e *corev1alpha1.Exec) *batchv1.Job {
 	return job
 }
 
-func (r *KubernetesRuntime) getJobWatcher(job *batchv1.Job) watch.Interface {
-	watcher, _ := r.kubernetesClient.BatchV1().Jobs(job.Namespace).Watch(metav1.ListOptions{
+func (r *KubernetesRuntime) getJobWatcher(job *batchv1.Job) (watch.Interface, error) {
+	return r.kubernetesClient.BatchV1().Jobs(job.Namespace).Watch(metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("metadata.name=%s", job.Name),
 	})
-	return watcher
 }
 
-func (r *KubernetesRuntime) watchJob(w io.WriteCloser, result chan int, jobDefinition *batchv1.Job) {
+func (r *KubernetesRuntime) deleteJob(job *batchv1.Job) error {
+	propagation := metav1.DeletePropagationBackground
+	err := r.kubernetesClient.BatchV1().Jobs(job.Namespace).Delete(job.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *KubernetesRuntime) watchJob(ctx context.Context, w io.WriteCloser, handle *execution.Handle, jobDefinition *batchv1.Job) {
+	defer w.Close()
 	finish := func(job *batchv1.Job) bool {
-		// Successfully completed a single instance of a job
+		// Stuck Job was already stopped
+		if reason, ok := job.Annotations[failureReasonAnnotation]; ok {
+			handle.Fail(1, reason, job.Annotations[failureMessageAnnotation])
+			return true
+		}
 		for _, condition := range job.Status.Conditions {
-			if condition.Type == batchv1.JobFailed || condition.Type == batchv1.JobComplete {
-				log.Infof("Job: %s has no active Pods running", job.Name)
-				/*
-					output, _ := r.clientset.CoreV1().Pods(config.Namespace).GetLogs(job.ObjectMeta.Name, &corev1.PodLogOptions{
-						Follow: true,
-					}).Stream()
-					io.Copy(w, output)
-
-					// Cleanup
-					output.Close()
-				*/
-				w.Close()
-
-				// Exit code is N where N is the number of Pods that failed. If the job
-				// ran to completion, the exit code will be 0.
-				if condition.Type == batchv1.JobComplete {
-					result <- 0
-				} else {
-					result <- 1
-				}
-				return true
+			if condition.Type != batchv1.JobComplete && condition.Type != batchv1.JobFailed {
+				continue
 			}
+			log.Infof("Job: %s has no active Pods running", job.Name)
+			pods := r.jobPods(job)
+			observeQueueWait(job, pods)
+			traceJob(ctx, tracing.Tracer(), job, pods)
+			r.archiveLogs(job, pods)
+			if condition.Type == batchv1.JobComplete {
+				handle.Finish(0)
+			} else {
+				r.fail(job, handle, diagnoseFailure(job, pods))
+			}
+			return true
 		}
 		return false
 	}
 
-	watcher := r.getJobWatcher(jobDefinition)
-	defer watcher.Stop()
-	results := watcher.ResultChan()
-
-	currentJob, _ := r.kubernetesClient.BatchV1().Jobs(jobDefinition.Namespace).Get(jobDefinition.GetName(), metav1.GetOptions{})
-	if finish(currentJob) {
+	currentJob := jobDefinition
+	var watcher watch.Interface
+	var results <-chan watch.Event
+	defer func() {
+		if watcher != nil {
+			watcher.Stop()
+		}
+	}()
+	// rewatch starts a new watch of the Job and checks whether it finished
+	// while it wasn't watched. Watches are closed by the API server after a
+	// timeout, so the Job is watched again every time its watch is closed.
+	rewatch := func() bool {
+		if watcher != nil {
+			watcher.Stop()
+		}
+		var err error
+		if watcher, err = r.getJobWatcher(currentJob); err != nil {
+			// Job is watched again on the next check of stuck Pods
+			log.Errorf("Failed to watch job %s: %s", currentJob.Name, err)
+			watcher, results = nil, nil
+		} else {
+			results = watcher.ResultChan()
+		}
+		job, err := r.kubernetesClient.BatchV1().Jobs(currentJob.Namespace).Get(currentJob.GetName(), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			log.Infof("Job: %s was deleted", currentJob.Name)
+			handle.Finish(1)
+			return true
+		}
+		if err != nil {
+			log.Errorf("Failed to get job %s: %s", currentJob.Name, err)
+			return false
+		}
+		currentJob = job
+		return finish(job)
+	}
+	if rewatch() {
 		return
 	}
 
-	for event := range results {
-		job, _ := event.Object.(*batchv1.Job)
-		log.Infof("Job: %s active: %d, succeeded: %d, failed: %d", job.Name, job.Status.Active, job.Status.Succeeded, job.Status.Failed)
-		if finish(job) {
-			log.Infof("Finished job watcher for %s", job.Name)
+	// Pods stuck before they start don't fail the Job, so they are checked periodically
+	ticker := time.NewTicker(stuckCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Job keeps running and is recovered by the next execution with the same name
+			return
+		case <-ticker.C:
+			if watcher == nil && rewatch() {
+				return
+			}
+			stuck := diagnoseStuck(r.jobPods(currentJob), time.Now(), config.StuckGracePeriod)
+			if stuck == nil {
+				continue
+			}
+			if err := r.stopJob(currentJob, *stuck); err != nil {
+				log.Errorf("Failed to stop stuck job %s: %s", currentJob.Name, err)
+				continue
+			}
+			r.fail(currentJob, handle, *stuck)
 			return
+		case event, ok := <-results:
+			if !ok {
+				if rewatch() {
+					return
+				}
+				continue
+			}
+			job, ok := event.Object.(*batchv1.Job)
+			if !ok {
+				continue
+			}
+			if event.Type == watch.Deleted {
+				// Job was cancelled or deleted with its Play
+				log.Infof("Job: %s was deleted", job.Name)
+				handle.Finish(1)
+				return
+			}
+			currentJob = job
+			log.Infof("Job: %s active: %d, succeeded: %d, failed: %d", job.Name, job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+			if finish(job) {
+				log.Infof("Finished job watcher for %s", job.Name)
+				return
+			}
 		}
 	}
 }
 
-func (r *KubernetesRuntime) updateStatus(play corev1alpha1.Play, transform func(*corev1alpha1.Play)) error {
-	// Accessing frame status map is unsafe
-	updateLock.Lock()
-	defer updateLock.Unlock()
-	instance := corev1alpha1.Play{}
-	config.Client.Get(context.TODO(), types.NamespacedName{Namespace: play.Namespace, Name: play.Name}, &instance)
-	transform(&instance)
-	return config.Client.Status().Update(context.TODO(), &instance)
+// stopJob stops Pods of the Job and records the failure on the Job, so that
+// every replica observes the Job as failed
+func (r *KubernetesRuntime) stopJob(job *batchv1.Job, f failure) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				failureReasonAnnotation:  f.reason,
+				failureMessageAnnotation: f.message,
+			},
+		},
+		"spec": map[string]interface{}{"parallelism": 0},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.kubernetesClient.BatchV1().Jobs(job.Namespace).Patch(job.Name, types.MergePatchType, patch)
+	return err
 }
 
-// UpdatePlayPhase updates the phase of a Play
-func (r *KubernetesRuntime) UpdatePlayPhase(play corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) error {
-	return r.updateStatus(play, func(instance *corev1alpha1.Play) {
-		instance.Status.Phase = phase
+// Observe returns executions of Jobs in the namespace with the labels, keyed
+// by names of the Jobs. Status of the executions is read from the Jobs, so it
+// doesn't depend on watches of executions started by this process.
+func (r *KubernetesRuntime) Observe(ctx context.Context, namespace string, selector map[string]string) (map[string]execution.Observation, error) {
+	jobs, err := r.kubernetesClient.BatchV1().Jobs(namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
 	})
+	if err != nil {
+		return nil, err
+	}
+	observations := make(map[string]execution.Observation)
+	for i := range jobs.Items {
+		observations[jobs.Items[i].Name] = r.observeJob(&jobs.Items[i])
+	}
+	return observations, nil
 }
 
-// UpdateFrameResult updates the results of a Frame in the Play
-func (r *KubernetesRuntime) UpdateFrameResult(play corev1alpha1.Play, ID string, result int) error {
-	return r.updateStatus(play, func(instance *corev1alpha1.Play) {
-		if instance.Status.Frames == nil {
-			instance.Status.Frames = make(map[string]int)
+// observeJob returns the status of the execution of the Job. Causes of
+// failures are diagnosed from Pods of failed Jobs.
+func (r *KubernetesRuntime) observeJob(job *batchv1.Job) execution.Observation {
+	// Every failed Pod of the Job is a failed attempt
+	observation := execution.Observation{Failed: int(job.Status.Failed)}
+	if job.Status.StartTime != nil {
+		observation.StartTime = job.Status.StartTime.Time
+	}
+	if job.Status.CompletionTime != nil {
+		observation.CompletionTime = job.Status.CompletionTime.Time
+	}
+	if reason, ok := job.Annotations[failureReasonAnnotation]; ok {
+		observation.Status = execution.Status{
+			Finished: true,
+			ExitCode: 1,
+			Reason:   reason,
+			Message:  job.Annotations[failureMessageAnnotation],
+		}
+		return observation
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
 		}
-		instance.Status.Frames[ID] = result
+		switch condition.Type {
+		case batchv1.JobComplete:
+			observation.Status = execution.Status{Finished: true}
+		case batchv1.JobFailed:
+			f := diagnoseFailure(job, r.jobPods(job))
+			observation.Status = execution.Status{Finished: true, ExitCode: f.exitCode, Reason: f.reason, Message: f.message}
+			// Completion time is set only on Jobs which complete
+			observation.CompletionTime = condition.LastTransitionTime.Time
+		}
+	}
+	return observation
+}
+
+// CancelAll deletes Jobs in the namespace with the labels
+func (r *KubernetesRuntime) CancelAll(ctx context.Context, namespace string, selector map[string]string) error {
+	jobs, err := r.kubernetesClient.BatchV1().Jobs(namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
 	})
+	if err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		if err := r.deleteJob(&jobs.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jobPods lists Pods created for the Job
+func (r *KubernetesRuntime) jobPods(job *batchv1.Job) []corev1.Pod {
+	pods, err := r.kubernetesClient.CoreV1().Pods(job.Namespace).List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("controller-uid=%s", job.UID),
+	})
+	if err != nil {
+		log.Errorf("Failed to list pods of job %s: %s", job.Name, err)
+		return nil
+	}
+	return pods.Items
+}
+
+// observeQueueWait observes the time from the creation of the Job until the
+// first container of its Pods started
+func observeQueueWait(job *batchv1.Job, pods []corev1.Pod) {
+	var started time.Time
+	for _, pod := range pods {
+		for _, status := range containerStatuses(pod) {
+			var startedAt metav1.Time
+			if status.State.Running != nil {
+				startedAt = status.State.Running.StartedAt
+			} else if status.State.Terminated != nil {
+				startedAt = status.State.Terminated.StartedAt
+			}
+			if !startedAt.IsZero() && (started.IsZero() || startedAt.Time.Before(started)) {
+				started = startedAt.Time
+			}
+		}
+	}
+	if !started.IsZero() {
+		metrics.QueueWait.Observe(started.Sub(job.CreationTimestamp.Time).Seconds())
+	}
+}
+
+// fail finishes the execution with the diagnosed failure and reports it with
+// an Event on the Play owning the Job
+func (r *KubernetesRuntime) fail(job *batchv1.Job, handle *execution.Handle, f failure) {
+	log.Warnf("Job: %s failed with %s: %s", job.Name, f.reason, f.message)
+	if owner := metav1.GetControllerOf(job); owner != nil && r.recorder != nil {
+		r.recorder.Eventf(&corev1.ObjectReference{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Namespace:  job.Namespace,
+			Name:       owner.Name,
+			UID:        owner.UID,
+		}, corev1.EventTypeWarning, f.reason, "Job %s failed: %s", job.Name, f.message)
+	}
+	handle.Fail(f.exitCode, f.reason, f.message)
+}
+
+// UpdatePlayPhase updates the phase of a Play
+func (r *KubernetesRuntime) UpdatePlayPhase(ctx context.Context, play corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) error {
+	return PatchPlayPhase(ctx, config.Client, play, phase)
+}
+
+// UpdateFrameResult updates the results of a Frame in the Play
+func (r *KubernetesRuntime) UpdateFrameResult(ctx context.Context, play corev1alpha1.Play, ID string, result int, failure *corev1alpha1.FrameFailure) error {
+	return PatchFrameResult(ctx, config.Client, play, ID, result, failure)
 }
@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestJob(name string, annotations map[string]string, conditions ...batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   "default",
+		Labels:      map[string]string{"core.kuberik.io/play": "play"},
+		Annotations: annotations,
+	}}
+	for _, condition := range conditions {
+		job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{Type: condition, Status: corev1.ConditionTrue})
+	}
+	return job
+}
+
+func TestObserve(t *testing.T) {
+	other := newTestJob("other", nil, batchv1.JobComplete)
+	other.Labels["core.kuberik.io/play"] = "other"
+	running := newTestJob("running", nil)
+	running.Status.Failed = 2
+	started := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	completed := metav1.NewTime(started.Add(30 * time.Second))
+	complete := newTestJob("complete", nil, batchv1.JobComplete)
+	complete.Status.StartTime = &started
+	complete.Status.CompletionTime = &completed
+	r := &KubernetesRuntime{kubernetesClient: kubefake.NewSimpleClientset(
+		running,
+		complete,
+		newTestJob("failed", nil, batchv1.JobFailed),
+		newTestJob("stuck", map[string]string{failureReasonAnnotation: execution.ReasonUnschedulable, failureMessageAnnotation: "No nodes"}),
+		other,
+	)}
+
+	observations, err := r.Observe(context.TODO(), "default", map[string]string{"core.kuberik.io/play": "play"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(observations) != 4 {
+		t.Errorf("Expected executions of the Play only, got %v", observations)
+	}
+	if observations["running"].Finished {
+		t.Error("Expected running Job not to be finished")
+	}
+	if failed := observations["running"].Failed; failed != 2 {
+		t.Errorf("Expected 2 failed attempts of the running Job, got %d", failed)
+	}
+	if complete := observations["complete"]; !complete.Finished || complete.ExitCode != 0 {
+		t.Errorf("Expected complete Job to succeed, got %+v", complete)
+	}
+	if complete := observations["complete"]; complete.CompletionTime.Sub(complete.StartTime) != 30*time.Second {
+		t.Errorf("Expected complete Job to run for 30s, got %s", complete.CompletionTime.Sub(complete.StartTime))
+	}
+	if failed := observations["failed"]; !failed.Finished || failed.ExitCode == 0 || failed.Reason != execution.ReasonError {
+		t.Errorf("Expected failed Job to fail with an error, got %+v", failed)
+	}
+	if stuck := observations["stuck"]; !stuck.Finished || stuck.Reason != execution.ReasonUnschedulable || stuck.Message != "No nodes" {
+		t.Errorf("Expected stopped Job to fail with the recorded failure, got %+v", stuck)
+	}
+
+	if err := r.CancelAll(context.TODO(), "default", map[string]string{"core.kuberik.io/play": "play"}); err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ := r.kubernetesClient.BatchV1().Jobs("default").List(metav1.ListOptions{})
+	if len(jobs.Items) != 1 || jobs.Items[0].Name != "other" {
+		t.Errorf("Expected only Jobs of the Play to be deleted, got %v", jobs.Items)
+	}
+}
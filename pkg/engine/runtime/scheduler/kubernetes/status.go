@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PatchPlayStatus merges the fields into the status of the Play with a JSON
+// merge patch. Patches don't carry the resource version of the Play, so patches
+// of different fields or frames of any Plays are applied concurrently without
+// reading the Play first, and they are never rejected with a conflict.
+func PatchPlayStatus(ctx context.Context, c client.Client, play corev1alpha1.Play, status map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+	instance := &corev1alpha1.Play{ObjectMeta: metav1.ObjectMeta{Namespace: play.Namespace, Name: play.Name}}
+	return c.Status().Patch(ctx, instance, client.ConstantPatch(types.MergePatchType, patch))
+}
+
+// PatchPlayPhase sets the phase of the Play
+func PatchPlayPhase(ctx context.Context, c client.Client, play corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) error {
+	return PatchPlayStatus(ctx, c, play, map[string]interface{}{"phase": phase})
+}
+
+// PatchFrameResult sets the result of a Frame in the Play and the cause of its
+// failure if it's set, leaving results of other frames intact
+func PatchFrameResult(ctx context.Context, c client.Client, play corev1alpha1.Play, ID string, result int, failure *corev1alpha1.FrameFailure) error {
+	return PatchPlayStatus(ctx, c, play, FrameResultPatch(ID, result, failure))
+}
+
+// FrameResultPatch returns the status merge patch setting the result of a Frame
+func FrameResultPatch(ID string, result int, failure *corev1alpha1.FrameFailure) map[string]interface{} {
+	patch := map[string]interface{}{"frames": map[string]int{ID: result}}
+	if failure != nil {
+		patch["frameFailures"] = map[string]corev1alpha1.FrameFailure{ID: *failure}
+	}
+	return patch
+}
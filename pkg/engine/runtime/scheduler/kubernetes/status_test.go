@@ -0,0 +1,109 @@
+package kubernetes_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kuberik/kuberik/pkg/apis"
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/kubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// apiLatency emulates a round trip to the API server
+const apiLatency = time.Millisecond
+
+// latencyClient delays status writes of the client like an API server would
+type latencyClient struct {
+	client.Client
+}
+
+func (c latencyClient) Status() client.StatusWriter {
+	return latencyStatusWriter{c.Client.Status()}
+}
+
+type latencyStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w latencyStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	time.Sleep(apiLatency)
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func newPlays(t testing.TB, count int) (client.Client, []corev1alpha1.Play) {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	var plays []corev1alpha1.Play
+	var objs []runtime.Object
+	for i := 0; i < count; i++ {
+		play := corev1alpha1.Play{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("play-%d", i)}}
+		plays = append(plays, play)
+		objs = append(objs, play.DeepCopy())
+	}
+	return fakeclient.NewFakeClientWithScheme(s, objs...), plays
+}
+
+// patchFrames concurrently records results of frames of all Plays
+func patchFrames(t testing.TB, c client.Client, plays []corev1alpha1.Play, frames int) {
+	var wg sync.WaitGroup
+	for _, play := range plays {
+		for i := 0; i < frames; i++ {
+			wg.Add(1)
+			go func(play corev1alpha1.Play, ID string) {
+				defer wg.Done()
+				if err := kubernetes.PatchFrameResult(context.TODO(), c, play, ID, 1, nil); err != nil {
+					t.Error(err)
+				}
+			}(play, fmt.Sprintf("frame-%d", i))
+		}
+	}
+	wg.Wait()
+}
+
+func TestPatchFrameResult(t *testing.T) {
+	c, plays := newPlays(t, 3)
+	patchFrames(t, c, plays, 20)
+	if err := kubernetes.PatchPlayPhase(context.TODO(), c, plays[0], corev1alpha1.PlayComplete); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, play := range plays {
+		instance := &corev1alpha1.Play{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: play.Namespace, Name: play.Name}, instance); err != nil {
+			t.Fatal(err)
+		}
+		if len(instance.Status.Frames) != 20 {
+			t.Errorf("Expected results of 20 frames in %s, got %v", play.Name, instance.Status.Frames)
+		}
+	}
+	instance := &corev1alpha1.Play{}
+	c.Get(context.TODO(), types.NamespacedName{Namespace: plays[0].Namespace, Name: plays[0].Name}, instance)
+	if instance.Status.Phase != corev1alpha1.PlayComplete || len(instance.Status.Frames) != 20 {
+		t.Errorf("Expected phase to be patched without frames being lost, got %v", instance.Status)
+	}
+}
+
+// BenchmarkPatchFrameResult measures recording of frame results of concurrent
+// Plays. Status writes don't wait for each other, so with 100 Plays an operation
+// takes a fraction of the 500 round trips it would take if they were serialized.
+func BenchmarkPatchFrameResult(b *testing.B) {
+	for _, count := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("plays=%d", count), func(b *testing.B) {
+			c, plays := newPlays(b, count)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				patchFrames(b, latencyClient{c}, plays, 5)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package kubernetes
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// traceJob records scheduling of the Pods of the finished Job and runs of their
+// containers as spans in the context. Timestamps of the spans are taken from the
+// status of the Pods, since the Job is only observed after it finished.
+func traceJob(ctx context.Context, tracer trace.Tracer, job *batchv1.Job, pods []corev1.Pod) {
+	for _, pod := range pods {
+		podAttribute := attribute.String("kuberik.pod", pod.Name)
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type != corev1.PodScheduled || condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			_, span := tracer.Start(ctx, "PodScheduling",
+				trace.WithTimestamp(job.CreationTimestamp.Time),
+				trace.WithAttributes(podAttribute, attribute.String("kuberik.node", pod.Spec.NodeName)),
+			)
+			span.End(trace.WithTimestamp(condition.LastTransitionTime.Time))
+		}
+		for _, status := range containerStatuses(pod) {
+			terminated := status.State.Terminated
+			if terminated == nil || terminated.StartedAt.IsZero() {
+				continue
+			}
+			_, span := tracer.Start(ctx, "ContainerRun",
+				trace.WithTimestamp(terminated.StartedAt.Time),
+				trace.WithAttributes(
+					podAttribute,
+					attribute.String("kuberik.container", status.Name),
+					attribute.Int("kuberik.exit_code", int(terminated.ExitCode)),
+				),
+			)
+			if terminated.ExitCode != 0 {
+				span.SetStatus(codes.Error, terminated.Reason)
+			}
+			span.End(trace.WithTimestamp(terminated.FinishedAt.Time))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTraceJob(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job", CreationTimestamp: metav1.NewTime(created)}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "job-abcde"},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{
+				Type:               corev1.PodScheduled,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(created.Add(2 * time.Second)),
+			}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "main",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode:   1,
+					StartedAt:  metav1.NewTime(created.Add(5 * time.Second)),
+					FinishedAt: metav1.NewTime(created.Add(10 * time.Second)),
+				}},
+			}},
+		},
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "Frame")
+	traceJob(ctx, tracer, job, []corev1.Pod{pod})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	for i, expected := range []struct {
+		name       string
+		start, end time.Duration
+	}{
+		{"PodScheduling", 0, 2 * time.Second},
+		{"ContainerRun", 5 * time.Second, 10 * time.Second},
+	} {
+		span := spans[i]
+		if span.Name != expected.name {
+			t.Errorf("Expected span %s, got %s", expected.name, span.Name)
+		}
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Span %s isn't a child of the frame span", span.Name)
+		}
+		if !span.StartTime.Equal(created.Add(expected.start)) || !span.EndTime.Equal(created.Add(expected.end)) {
+			t.Errorf("Span %s has unexpected timestamps %s - %s", span.Name, span.StartTime, span.EndTime)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory creates a Scheduler. It's called once, when the Scheduler is first used.
+type Factory func() (Scheduler, error)
+
+// Registry holds Schedulers by name. Frames select a Scheduler with their
+// runtime field and fall back to the default one.
+type Registry struct {
+	// Default is the name of the Scheduler used by frames which don't specify a runtime
+	Default string
+
+	lock       sync.Mutex
+	factories  map[string]Factory
+	schedulers map[string]Scheduler
+}
+
+// DefaultRegistry is the Registry with the built-in Schedulers used by the engine
+var DefaultRegistry = NewRegistry(KubernetesScheduler)
+
+// NewRegistry creates an empty Registry with the default Scheduler name
+func NewRegistry(defaultScheduler string) *Registry {
+	return &Registry{
+		Default:    defaultScheduler,
+		factories:  make(map[string]Factory),
+		schedulers: make(map[string]Scheduler),
+	}
+}
+
+// Register adds a Scheduler created by the factory to the DefaultRegistry
+func Register(name string, factory Factory) {
+	DefaultRegistry.Register(name, factory)
+}
+
+// Register adds a Scheduler created by the factory under the name
+func (r *Registry) Register(name string, factory Factory) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.factories[name] = factory
+	delete(r.schedulers, name)
+}
+
+// Add adds an existing Scheduler under the name
+func (r *Registry) Add(name string, s Scheduler) {
+	r.Register(name, func() (Scheduler, error) {
+		return s, nil
+	})
+}
+
+// Get returns the Scheduler registered under the name or the default one if
+// the name is empty.
+func (r *Registry) Get(name string) (Scheduler, error) {
+	if name == "" {
+		name = r.Default
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if s, ok := r.schedulers[name]; ok {
+		return s, nil
+	}
+	factory, ok := r.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown scheduler: %s", name)
+	}
+	s, err := factory()
+	if err != nil {
+		return nil, err
+	}
+	r.schedulers[name] = s
+	return s, nil
+}
@@ -2,45 +2,93 @@ package scheduler
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"io"
 
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
 	"github.com/kuberik/kuberik/pkg/engine/config"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/container"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
 	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/kubernetes"
 	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/shell"
-	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-var Engine Scheduler
+// Names of the built-in schedulers
+const (
+	KubernetesScheduler = "kubernetes"
+	ContainerScheduler  = "container"
+	ShellScheduler      = "shell"
+)
+
+// blank assignments to verify that schedulers implement Scheduler
+var _ Scheduler = &shell.Shell{}
+var _ Scheduler = &kubernetes.KubernetesRuntime{}
+var _ Scheduler = &container.ContainerRuntime{}
 
+// Scheduler runs executions of frames and records results of Plays. Name,
+// namespace, labels and owners of executions are set by the metadata. Executions
+// stop being watched when the context passed to Run is done; whether they keep
+// running is up to the Scheduler. Cancel of the returned Execution stops it.
+// Results of failed frames are recorded together with the cause of the failure.
 type Scheduler interface {
-	Run(name string, namespace corev1.Namespace, exec corev1alpha1.Exec) (io.Reader, chan int, error)
-	UpdatePlayPhase(play corev1alpha1.Play, status corev1alpha1.PlayPhaseType) error
-	UpdateFrameResult(play corev1alpha1.Play, ID string, result int) error
+	Run(ctx context.Context, meta metav1.ObjectMeta, exec corev1alpha1.Exec) (execution.Execution, error)
+	UpdatePlayPhase(ctx context.Context, play corev1alpha1.Play, status corev1alpha1.PlayPhaseType) error
+	UpdateFrameResult(ctx context.Context, play corev1alpha1.Play, ID string, result int, failure *corev1alpha1.FrameFailure) error
+}
+
+// Observer is implemented by Schedulers whose executions outlive the process
+// which started them, such as Jobs on Kubernetes. Player reads the status of
+// such executions from the Scheduler on every reconciliation, so it doesn't
+// depend on handles of executions it started itself. Handles are then only
+// used to follow logs.
+type Observer interface {
+	// Observe returns executions in the namespace with the labels, keyed by their names
+	Observe(ctx context.Context, namespace string, labels map[string]string) (map[string]execution.Observation, error)
+	// CancelAll stops executions in the namespace with the labels
+	CancelAll(ctx context.Context, namespace string, labels map[string]string) error
+}
+
+// blank assignments to verify that schedulers implement Observer
+var _ Observer = &kubernetes.KubernetesRuntime{}
+
+func init() {
+	Register(KubernetesScheduler, func() (Scheduler, error) {
+		return kubernetes.NewKubernetesRuntime(config.Config), nil
+	})
+	Register(ContainerScheduler, func() (Scheduler, error) {
+		return container.NewContainerRuntime(config.ContainerHost, config.WorkDir, config.Client)
+	})
+	Register(ShellScheduler, func() (Scheduler, error) {
+		return shell.NewShell(config.WorkDir, config.Client), nil
+	})
 }
 
-func RunSync(exec corev1alpha1.Exec) ([]byte, error) {
+func RunSync(ctx context.Context, exec corev1alpha1.Exec) ([]byte, error) {
 	shell := &shell.Shell{}
-	out, result, err := shell.Run("", "", exec)
+	execution, err := shell.Run(ctx, metav1.ObjectMeta{Name: "sync"}, exec)
 	if err != nil {
 		return []byte(""), err
 	}
 
 	buf := new(bytes.Buffer)
-	buf.ReadFrom(out)
+	buf.ReadFrom(execution.Logs())
 
-	exit := <-result
+	exit, err := execution.Wait(ctx)
+	if err != nil {
+		return []byte(""), err
+	}
 	if exit != 0 {
 		return []byte(""), fmt.Errorf("Exec failed with exit code: %d", exit)
 	}
 	return buf.Bytes(), nil
 }
 
-func InitEngine() {
-	Engine = kubernetes.NewKubernetesRuntime(config.Config)
-}
-
-func RunAsync(name string, namespace corev1.Namespace, exec corev1alpha1.Exec) (io.Reader, chan int, error) {
-	return Engine.Run(name, namespace, exec)
+// InitEngine selects the default Scheduler of the DefaultRegistry from the configuration
+func InitEngine() error {
+	if config.Scheduler != "" {
+		DefaultRegistry.Default = config.Scheduler
+	}
+	_, err := DefaultRegistry.Get("")
+	return err
 }
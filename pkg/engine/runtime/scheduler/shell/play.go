@@ -0,0 +1,227 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/kubernetes"
+	"github.com/kuberik/kuberik/pkg/randutils"
+	"github.com/tidwall/gjson"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type play struct {
+	status corev1alpha1.PlayStatus
+	done   chan struct{}
+}
+
+// Provision prepares the Play for the local execution the same way the Play
+// controller does on the cluster. Vars are resolved into an in-memory ConfigMap
+// and volume claim templates are provisioned as directories.
+func (s *Shell) Provision(instance *corev1alpha1.Play) error {
+	if instance.Namespace == "" {
+		instance.Namespace = defaultNamespace
+	}
+	if instance.Name == "" {
+		instance.Name = instance.GenerateName + randutils.RandSized(5)
+	}
+
+	var frames []*corev1alpha1.Frame
+	for k := range instance.Spec.Screenplays {
+		for i := range instance.Spec.Screenplays[k].Scenes {
+			for j := range instance.Spec.Screenplays[k].Scenes[i].Frames {
+				frames = append(frames, &instance.Spec.Screenplays[k].Scenes[i].Frames[j])
+			}
+		}
+	}
+	for i, id := range randutils.RandList(len(frames)) {
+		if frames[i].ID == "" {
+			frames[i].ID = id
+		}
+	}
+
+	vars := make(map[string]string)
+	for i, v := range instance.Spec.Vars {
+		if v.ValueFrom != nil && v.ValueFrom.InputRef != nil {
+			instance.Spec.Vars[i].Value = gjson.Get(instance.Spec.Input, v.ValueFrom.InputRef.GJSONPath).String()
+		}
+		vars[v.Name] = instance.Spec.Vars[i].Value
+	}
+	instance.Status.VarsConfigMap = fmt.Sprintf("%s-vars", instance.Name)
+	s.SetConfigMap(instance.Namespace, instance.Status.VarsConfigMap, vars)
+
+	if instance.Status.ProvisionedVolumes == nil {
+		instance.Status.ProvisionedVolumes = make(map[string]string)
+	}
+	for _, volumeClaimTemplate := range instance.Spec.VolumeClaimTemplates {
+		claimName := fmt.Sprintf("%s-%s", instance.Name, volumeClaimTemplate.Name)
+		if err := os.MkdirAll(s.path(instance.Namespace, "volumes", claimName), 0755); err != nil {
+			return err
+		}
+		instance.Status.ProvisionedVolumes[volumeClaimTemplate.Name] = claimName
+	}
+
+	instance.Status.Phase = corev1alpha1.PlayRunning
+	if instance.Status.Frames == nil {
+		instance.Status.Frames = make(map[string]int)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.plays == nil {
+		s.plays = make(map[string]*play)
+	}
+	p := &play{done: make(chan struct{})}
+	instance.Status.DeepCopyInto(&p.status)
+	s.plays[playKey(instance.Namespace, instance.Name)] = p
+	return s.save(instance.Namespace, instance.Name, p)
+}
+
+// SetConfigMap stores data of a ConfigMap which can be referenced by the frames
+func (s *Shell) SetConfigMap(namespace, name string, data map[string]string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.configMaps == nil {
+		s.configMaps = make(map[string]map[string]string)
+	}
+	s.configMaps[playKey(namespace, name)] = data
+}
+
+func (s *Shell) configMap(namespace, name string) (map[string]string, bool) {
+	s.lock.Lock()
+	data, ok := s.configMaps[playKey(namespace, name)]
+	s.lock.Unlock()
+	if ok || s.Client == nil {
+		return data, ok
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := s.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+		return nil, false
+	}
+	return configMap.Data, true
+}
+
+// writeConfigMap writes keys of the ConfigMap as files in the directory
+func (s *Shell) writeConfigMap(namespace, name, dir string) error {
+	data, ok := s.configMap(namespace, name)
+	if !ok {
+		return fmt.Errorf("ConfigMap %s not found", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for k, v := range data {
+		if err := ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status returns the status of a Play executed by the scheduler
+func (s *Shell) Status(namespace, name string) (corev1alpha1.PlayStatus, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	p, ok := s.plays[playKey(namespace, name)]
+	if !ok {
+		return corev1alpha1.PlayStatus{}, false
+	}
+	return *p.status.DeepCopy(), true
+}
+
+// Wait blocks until the Play finishes or the context is done and returns its status
+func (s *Shell) Wait(ctx context.Context, namespace, name string) (corev1alpha1.PlayStatus, error) {
+	s.lock.Lock()
+	p, ok := s.plays[playKey(namespace, name)]
+	s.lock.Unlock()
+	if !ok {
+		return corev1alpha1.PlayStatus{}, fmt.Errorf("Play %s wasn't provisioned", name)
+	}
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return corev1alpha1.PlayStatus{}, ctx.Err()
+	}
+	status, _ := s.Status(namespace, name)
+	return status, nil
+}
+
+// update transforms the status of a Play provisioned by the Shell. Status of
+// other Plays is patched on the cluster.
+func (s *Shell) update(ctx context.Context, instance corev1alpha1.Play, transform func(*corev1alpha1.PlayStatus), patch map[string]interface{}) error {
+	s.lock.Lock()
+	p, ok := s.plays[playKey(instance.Namespace, instance.Name)]
+	if !ok {
+		s.lock.Unlock()
+		if s.Client == nil {
+			return fmt.Errorf("Play %s wasn't provisioned", instance.Name)
+		}
+		return kubernetes.PatchPlayStatus(ctx, s.Client, instance, patch)
+	}
+	defer s.lock.Unlock()
+	transform(&p.status)
+	switch p.status.Phase {
+	case corev1alpha1.PlayComplete, corev1alpha1.PlayFailed, corev1alpha1.PlayError:
+		select {
+		case <-p.done:
+		default:
+			close(p.done)
+		}
+	}
+	return s.save(instance.Namespace, instance.Name, p)
+}
+
+// UpdatePlayPhase updates the phase of a Play
+func (s *Shell) UpdatePlayPhase(ctx context.Context, instance corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) error {
+	return s.update(ctx, instance, func(status *corev1alpha1.PlayStatus) {
+		status.Phase = phase
+	}, map[string]interface{}{"phase": phase})
+}
+
+// UpdateFrameResult updates the results of a Frame in the Play
+func (s *Shell) UpdateFrameResult(ctx context.Context, instance corev1alpha1.Play, ID string, result int, failure *corev1alpha1.FrameFailure) error {
+	return s.update(ctx, instance, func(status *corev1alpha1.PlayStatus) {
+		if status.Frames == nil {
+			status.Frames = make(map[string]int)
+		}
+		status.Frames[ID] = result
+		if failure != nil {
+			if status.FrameFailures == nil {
+				status.FrameFailures = make(map[string]corev1alpha1.FrameFailure)
+			}
+			status.FrameFailures[ID] = *failure
+		}
+	}, kubernetes.FrameResultPatch(ID, result, failure))
+}
+
+// save writes the status of the Play to the local file
+func (s *Shell) save(namespace, name string, p *play) error {
+	statusJSON, err := json.MarshalIndent(p.status, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := s.path(namespace, "plays")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.json", name)), statusJSON, 0644)
+}
+
+func (s *Shell) path(elem ...string) string {
+	root := s.Dir
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "kuberik")
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+func playKey(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
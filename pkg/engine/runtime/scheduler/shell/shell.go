@@ -1,46 +1,378 @@
 package shell
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 
 	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/execution"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-type Shell struct{}
+const (
+	defaultNamespace = "default"
+	// exit code reported when a process can't be started
+	startFailedExitCode = 127
+)
 
-func (s *Shell) Run(namespace, name string, e corev1alpha1.Exec) (io.Reader, chan int, error) {
-	reader, writer := io.Pipe()
-	result := make(chan int)
+// Shell defines a Scheduler which executes Plays as processes on the local machine.
+// ConfigMaps and persistent volumes referenced by the frames are emulated with
+// directories under Dir, and results of Plays are kept in memory and written to Dir.
+type Shell struct {
+	// Dir is the root directory of executions. Temporary directory is used if empty.
+	Dir string
+	// Client is used for ConfigMaps and results of Plays which weren't provisioned
+	// by the Shell. Shell runs without a cluster if it's not set.
+	Client client.Client
+
+	lock       sync.Mutex
+	configMaps map[string]map[string]string
+	plays      map[string]*play
+}
+
+// NewShell creates a new Shell scheduler with executions in the directory
+func NewShell(dir string, c client.Client) *Shell {
+	return &Shell{Dir: dir, Client: c}
+}
 
-	var args []string
-	var command string
-	args = append(args, e.Template.Spec.Containers[0].Args...)
-	if execCommand := e.Template.Spec.Containers[0].Command; len(execCommand) > 0 {
-		args = append(execCommand[1:], args...)
-		command = execCommand[0]
+// Run executes the containers of the Exec as processes. Init containers are run
+// sequentially before all other containers, which run in parallel. Processes are
+// killed when the execution is cancelled or the context is done.
+func (s *Shell) Run(ctx context.Context, meta metav1.ObjectMeta, e corev1alpha1.Exec) (execution.Execution, error) {
+	name := meta.Name
+	ns := meta.Namespace
+	if ns == "" {
+		ns = defaultNamespace
+	}
+	workDir, volumes, err := s.Volumes(ns, name, e.Template.Spec.Volumes)
+	if err != nil {
+		return nil, err
 	}
-	cmd := exec.Command(command, args...)
-	cmd.Stdout = writer
-	cmd.Stderr = writer
 
-	cmd.Start()
+	var initCmds, cmds []*exec.Cmd
+	for _, container := range e.Template.Spec.InitContainers {
+		cmd, err := s.command(ns, workDir, volumes, container)
+		if err != nil {
+			return nil, err
+		}
+		initCmds = append(initCmds, cmd)
+	}
+	for _, container := range e.Template.Spec.Containers {
+		cmd, err := s.command(ns, workDir, volumes, container)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	if len(cmds) == 0 {
+		return nil, fmt.Errorf("Exec %s doesn't have any containers", name)
+	}
 
+	reader, writer := io.Pipe()
+	for _, cmd := range append(initCmds, cmds...) {
+		cmd.Stdout = writer
+		cmd.Stderr = writer
+	}
+
+	p := &processes{}
+	// Without init containers failures to start are reported right away
+	if len(initCmds) == 0 {
+		if err := p.startAll(cmds); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	handle := execution.NewHandle(reader, func() error {
+		cancel()
+		return nil
+	})
+	finished := make(chan struct{})
 	go func() {
-		err := cmd.Wait()
-		writer.Close()
-		// TODO This looks so stupid
-		if _, ok := err.(*exec.ExitError); ok {
-			result <- 1
-		} else {
-			result <- 0
+		select {
+		case <-ctx.Done():
+			p.kill()
+		case <-finished:
+		}
+	}()
+	go func() {
+		defer cancel()
+		exit := 0
+		for _, cmd := range initCmds {
+			if exit = p.run(writer, cmd); exit != 0 {
+				break
+			}
+		}
+		if exit == 0 {
+			if len(initCmds) > 0 {
+				if err := p.startAll(cmds); err != nil {
+					fmt.Fprintln(writer, err)
+					exit = startFailedExitCode
+				}
+			}
+			if exit == 0 {
+				exit = waitAll(cmds)
+			}
 		}
-		close(result)
+		close(finished)
+		writer.Close()
+		handle.Finish(exit)
 	}()
 
-	return reader, result, nil
+	return handle, nil
+}
+
+// processes tracks processes of an execution, so they can be killed together
+// with processes they started
+type processes struct {
+	lock    sync.Mutex
+	started []*exec.Cmd
+	killed  bool
 }
 
-func (r *Shell) UpdatePlayPhase(play corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) error {
+func (p *processes) start(cmd *exec.Cmd) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.killed {
+		return fmt.Errorf("Execution was cancelled")
+	}
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p.started = append(p.started, cmd)
 	return nil
 }
+
+func (p *processes) kill() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.killed = true
+	for _, cmd := range p.started {
+		if err := killProcessGroup(cmd); err != nil {
+			log.Warnf("Failed to kill process %d: %s", cmd.Process.Pid, err)
+		}
+	}
+}
+
+func (p *processes) startAll(cmds []*exec.Cmd) error {
+	for i, cmd := range cmds {
+		if err := p.start(cmd); err != nil {
+			for _, started := range cmds[:i] {
+				killProcessGroup(started)
+				started.Wait()
+			}
+			return fmt.Errorf("Failed to start %s: %s", cmd.Path, err)
+		}
+	}
+	return nil
+}
+
+func (p *processes) run(w io.Writer, cmd *exec.Cmd) int {
+	if err := p.start(cmd); err != nil {
+		fmt.Fprintf(w, "Failed to start %s: %s\n", cmd.Path, err)
+		return startFailedExitCode
+	}
+	return exitCode(cmd.Wait())
+}
+
+// waitAll waits for all commands and returns the first non-zero exit code
+func waitAll(cmds []*exec.Cmd) int {
+	exit := 0
+	for _, cmd := range cmds {
+		if code := exitCode(cmd.Wait()); exit == 0 {
+			exit = code
+		}
+	}
+	return exit
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	log.Errorf("Process failed: %s", err)
+	return 1
+}
+
+// command creates a process for the container. Command is taken from the
+// container command or first argument if the command is not set, since images
+// and their entrypoints don't exist on the local machine.
+func (s *Shell) command(namespace, workDir string, volumes map[string]string, container corev1.Container) (*exec.Cmd, error) {
+	env, err := s.Env(namespace, container)
+	if err != nil {
+		return nil, err
+	}
+	mounted, err := containerMounts(volumes, container)
+	if err != nil {
+		return nil, err
+	}
+	expand := func(value string) string {
+		return mounted.translate(ExpandEnv(value, env))
+	}
+
+	args := append(append([]string{}, container.Command...), container.Args...)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Container %s doesn't have a command", container.Name)
+	}
+	for i := range args {
+		args[i] = expand(args[i])
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = workDir
+	if container.WorkingDir != "" {
+		cmd.Dir = mounted.translate(container.WorkingDir)
+	}
+	cmd.Env = os.Environ()
+	for _, e := range env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", e.Name, mounted.translate(e.Value)))
+	}
+	for _, m := range mounted {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", mountEnvName(m.name), m.hostPath))
+	}
+	return cmd, nil
+}
+
+// Env resolves the environment of the container in order in which Kubernetes
+// populates it, so later values override the earlier ones.
+func (s *Shell) Env(namespace string, container corev1.Container) ([]corev1.EnvVar, error) {
+	var env []corev1.EnvVar
+	for _, from := range container.EnvFrom {
+		if from.ConfigMapRef == nil {
+			continue
+		}
+		data, ok := s.configMap(namespace, from.ConfigMapRef.Name)
+		if !ok {
+			if from.ConfigMapRef.Optional != nil && *from.ConfigMapRef.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("ConfigMap %s not found", from.ConfigMapRef.Name)
+		}
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			env = append(env, corev1.EnvVar{Name: from.Prefix + k, Value: data[k]})
+		}
+	}
+	for _, e := range container.Env {
+		value := ExpandEnv(e.Value, env)
+		if e.ValueFrom != nil && e.ValueFrom.ConfigMapKeyRef != nil {
+			ref := e.ValueFrom.ConfigMapKeyRef
+			data, _ := s.configMap(namespace, ref.Name)
+			v, ok := data[ref.Key]
+			if !ok && (ref.Optional == nil || !*ref.Optional) {
+				return nil, fmt.Errorf("Key %s of ConfigMap %s not found", ref.Key, ref.Name)
+			}
+			value = v
+		}
+		env = append(env, corev1.EnvVar{Name: e.Name, Value: value})
+	}
+	return env, nil
+}
+
+// ExpandEnv expands references $(VAR) to the variables of the environment
+func ExpandEnv(value string, env []corev1.EnvVar) string {
+	if !strings.Contains(value, "$(") {
+		return value
+	}
+	var replacements []string
+	for i := len(env) - 1; i >= 0; i-- {
+		replacements = append(replacements, fmt.Sprintf("$(%s)", env[i].Name), env[i].Value)
+	}
+	return strings.NewReplacer(replacements...).Replace(value)
+}
+
+type mount struct {
+	name     string
+	path     string
+	hostPath string
+	pattern  *regexp.Regexp
+}
+
+// mounts maps mount paths of containers to directories on the local machine
+type mounts []mount
+
+// translate replaces mount paths in the value with paths of the local directories
+func (m mounts) translate(value string) string {
+	for _, v := range m {
+		value = v.pattern.ReplaceAllString(value, "${1}"+strings.Replace(v.hostPath, "$", "$$", -1)+"${2}")
+	}
+	return value
+}
+
+// containerMounts maps mount paths of the container to the local directories of volumes.
+// Longer paths are translated first, so nested mounts take precedence.
+func containerMounts(volumes map[string]string, container corev1.Container) (mounts, error) {
+	var m mounts
+	for _, volumeMount := range container.VolumeMounts {
+		dir, ok := volumes[volumeMount.Name]
+		if !ok {
+			return nil, fmt.Errorf("Volume %s mounted by container %s not found", volumeMount.Name, container.Name)
+		}
+		if volumeMount.SubPath != "" {
+			dir = filepath.Join(dir, volumeMount.SubPath)
+		}
+		mountPath := strings.TrimSuffix(volumeMount.MountPath, "/")
+		m = append(m, mount{
+			name:     volumeMount.Name,
+			path:     mountPath,
+			hostPath: dir,
+			pattern:  regexp.MustCompile(`(^|[\s"'=:])` + regexp.QuoteMeta(mountPath) + `(/|$|[\s"';:])`),
+		})
+	}
+	sort.Slice(m, func(i, j int) bool { return len(m[i].path) > len(m[j].path) })
+	return m, nil
+}
+
+func mountEnvName(volume string) string {
+	return "KUBERIK_MOUNT_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(volume))
+}
+
+// Volumes prepares the working directory of the execution and local directories
+// of volumes, keyed by volume name.
+func (s *Shell) Volumes(namespace, name string, volumes []corev1.Volume) (string, map[string]string, error) {
+	workDir := s.path(namespace, "executions", name)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "", nil, err
+	}
+	dirs := make(map[string]string)
+	for _, v := range volumes {
+		var dir string
+		switch {
+		case v.PersistentVolumeClaim != nil:
+			dir = s.path(namespace, "volumes", v.PersistentVolumeClaim.ClaimName)
+		case v.HostPath != nil:
+			dir = v.HostPath.Path
+		case v.ConfigMap != nil:
+			dir = s.path(namespace, "configmaps", v.ConfigMap.Name)
+			if err := s.writeConfigMap(namespace, v.ConfigMap.Name, dir); err != nil {
+				return "", nil, err
+			}
+		default:
+			dir = filepath.Join(workDir, "volumes", v.Name)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", nil, err
+		}
+		dirs[v.Name] = dir
+	}
+	return workDir, dirs, nil
+}
@@ -0,0 +1,192 @@
+package shell_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/runtime"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler"
+	"github.com/kuberik/kuberik/pkg/engine/runtime/scheduler/shell"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func shellFrame(name, script string) corev1alpha1.Frame {
+	return corev1alpha1.Frame{
+		Name: name,
+		Action: &corev1alpha1.Exec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Command: []string{"sh", "-c", script},
+						Env: []corev1.EnvVar{
+							{Name: "TARGET", Value: "$(GREETING) world"},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "workspace", MountPath: "/workspace"},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestShellPlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberik-shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := shell.NewShell(dir, nil)
+	schedulers := scheduler.NewRegistry("shell")
+	schedulers.Add("shell", s)
+
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: "hello"},
+		Spec: corev1alpha1.PlaySpec{
+			Vars: corev1alpha1.Vars{{Name: "GREETING", Value: "hello"}},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "workspace"}},
+			},
+			Screenplays: []corev1alpha1.Screenplay{{
+				Name: "main",
+				Scenes: []corev1alpha1.Scene{
+					{Name: "write", Frames: []corev1alpha1.Frame{shellFrame("write", `echo "$TARGET" > /workspace/out`)}},
+					{Name: "check", Frames: []corev1alpha1.Frame{shellFrame("check", `test "$(cat /workspace/out)" = "$GREETING world" && test -f /kuberik/vars/GREETING`)}},
+				},
+			}},
+		},
+	}
+	if err := s.Provision(play); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.PlayWith(context.Background(), schedulers, *play); err != nil {
+		t.Fatal(err)
+	}
+	status, err := s.Wait(context.Background(), play.Namespace, play.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != corev1alpha1.PlayComplete {
+		t.Errorf("Play finished in phase %s, frames: %v", status.Phase, status.Frames)
+	}
+	if len(status.Frames) != 2 {
+		t.Errorf("Expected results of 2 frames, got %v", status.Frames)
+	}
+}
+
+func TestShellPlayFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberik-shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := shell.NewShell(dir, nil)
+	schedulers := scheduler.NewRegistry("shell")
+	schedulers.Add("shell", s)
+
+	frame := shellFrame("fail", "exit 3")
+	frame.Action.Template.Spec.Containers[0].VolumeMounts = nil
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: "fail"},
+		Spec: corev1alpha1.PlaySpec{
+			Screenplays: []corev1alpha1.Screenplay{{
+				Name:   "main",
+				Scenes: []corev1alpha1.Scene{{Name: "fail", Frames: []corev1alpha1.Frame{frame}}},
+			}},
+		},
+	}
+	if err := s.Provision(play); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.PlayWith(context.Background(), schedulers, *play); err != nil {
+		t.Fatal(err)
+	}
+	status, _ := s.Wait(context.Background(), play.Namespace, play.Name)
+	if status.Phase != corev1alpha1.PlayFailed {
+		t.Errorf("Play finished in phase %s", status.Phase)
+	}
+	if exit := status.Frames[play.Spec.Screenplays[0].Scenes[0].Frames[0].ID]; exit != 3 {
+		t.Errorf("Expected exit code 3, got %d", exit)
+	}
+}
+
+func TestShellCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberik-shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := shell.NewShell(dir, nil)
+	frame := shellFrame("sleep", "sleep 60")
+	frame.Action.Template.Spec.Containers[0].VolumeMounts = nil
+	execution, err := s.Run(context.Background(), metav1.ObjectMeta{Name: "sleep"}, *frame.Action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ioutil.ReadAll(execution.Logs())
+	if execution.Status().Finished {
+		t.Fatal("Execution finished before it was cancelled")
+	}
+	if err := execution.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	exit, err := execution.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Cancelled execution didn't finish: %s", err)
+	}
+	if exit == 0 {
+		t.Errorf("Expected cancelled execution to fail")
+	}
+}
+
+func TestShellPlayError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuberik-shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := shell.NewShell(dir, nil)
+	schedulers := scheduler.NewRegistry("shell")
+	schedulers.Add("shell", s)
+
+	// Container without a command can't be executed by the shell
+	frame := shellFrame("broken", "")
+	frame.Action.Template.Spec.Containers[0].Command = nil
+	frame.Action.Template.Spec.Containers[0].VolumeMounts = nil
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken"},
+		Spec: corev1alpha1.PlaySpec{
+			Screenplays: []corev1alpha1.Screenplay{{
+				Name:   "main",
+				Scenes: []corev1alpha1.Scene{{Name: "broken", Frames: []corev1alpha1.Frame{frame}}},
+			}},
+		},
+	}
+	if err := s.Provision(play); err != nil {
+		t.Fatal(err)
+	}
+	runtime.PlayWith(context.Background(), schedulers, *play)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := s.Wait(ctx, play.Namespace, play.Name)
+	if err != nil {
+		t.Fatalf("Wait didn't return after the Play errored: %s", err)
+	}
+	if status.Phase != corev1alpha1.PlayError {
+		t.Errorf("Play finished in phase %s", status.Phase)
+	}
+}
@@ -0,0 +1,176 @@
+package runtime
+
+import (
+	"context"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/engine/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// playTrace holds spans of a Play which are open while the Player plays it
+type playTrace struct {
+	// ctx holds the span of the Play
+	ctx context.Context
+	// contexts of scene spans keyed by scene name
+	scenes map[string]context.Context
+	// frame spans keyed by frame ID
+	frames map[string]trace.Span
+}
+
+func newPlayTrace(ctx context.Context) *playTrace {
+	return &playTrace{
+		ctx:    ctx,
+		scenes: make(map[string]context.Context),
+		frames: make(map[string]trace.Span),
+	}
+}
+
+// StartTrace starts the span of the Play as a child of the trace context in
+// the traceparent annotation of the Play, if it's set. Trace context of the
+// span is set in the annotation, so it has to be persisted with the Play for
+// replicas which recover the Play to continue its trace.
+func (p *Player) StartTrace(play *corev1alpha1.Play) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := types.NamespacedName{Namespace: play.Namespace, Name: play.Name}
+	p.endTrace(key)
+	opts := []trace.SpanStartOption{trace.WithAttributes(
+		attribute.String("kuberik.play", play.Name),
+		attribute.String("kuberik.namespace", play.Namespace),
+	)}
+	if play.Status.StartTime != nil {
+		opts = append(opts, trace.WithTimestamp(play.Status.StartTime.Time))
+	}
+	parent := tracing.ContextWithTraceparent(context.Background(), play.Annotations[tracing.TraceparentAnnotation])
+	ctx, _ := tracing.Tracer().Start(parent, "Play", opts...)
+	p.traces[key] = newPlayTrace(ctx)
+	if traceparent := tracing.Traceparent(ctx); traceparent != "" {
+		if play.Annotations == nil {
+			play.Annotations = make(map[string]string)
+		}
+		play.Annotations[tracing.TraceparentAnnotation] = traceparent
+	}
+}
+
+// playTrace returns the trace of the Play. Player which didn't start the trace,
+// such as a replica which recovered the Play, continues it from the trace
+// context of the Play span in the traceparent annotation. Lock must be held.
+func (p *Player) playTrace(play *corev1alpha1.Play) *playTrace {
+	key := types.NamespacedName{Namespace: play.Namespace, Name: play.Name}
+	if t, ok := p.traces[key]; ok {
+		return t
+	}
+	t := newPlayTrace(tracing.ContextWithTraceparent(context.Background(), play.Annotations[tracing.TraceparentAnnotation]))
+	p.traces[key] = t
+	return t
+}
+
+// startFrameSpan starts the span of an execution of the frame in the span of
+// its scene and returns the context of the span
+func (p *Player) startFrameSpan(ctx context.Context, play *corev1alpha1.Play, scene *corev1alpha1.Scene, frame *corev1alpha1.Frame, name string) context.Context {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	t := p.playTrace(play)
+	sceneCtx, ok := t.scenes[scene.Name]
+	if !ok {
+		sceneCtx, _ = tracing.Tracer().Start(t.ctx, "Scene", trace.WithAttributes(attribute.String("kuberik.scene", scene.Name)))
+		t.scenes[scene.Name] = sceneCtx
+	}
+	if span, ok := t.frames[frame.ID]; ok {
+		span.End()
+	}
+	frameCtx, span := tracing.Tracer().Start(sceneCtx, "Frame", trace.WithAttributes(
+		attribute.String("kuberik.frame", frame.Name),
+		attribute.String("kuberik.frame.id", frame.ID),
+		attribute.String("kuberik.execution", name),
+	))
+	t.frames[frame.ID] = span
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(frameCtx))
+}
+
+// endFrameSpan ends the span of the frame with the result of its execution
+func (p *Player) endFrameSpan(play *corev1alpha1.Play, frameID string, exitCode int, failure *corev1alpha1.FrameFailure) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	t := p.traces[types.NamespacedName{Namespace: play.Namespace, Name: play.Name}]
+	if t == nil || t.frames[frameID] == nil {
+		return
+	}
+	span := t.frames[frameID]
+	span.SetAttributes(attribute.Int("kuberik.exit_code", exitCode))
+	if failure != nil {
+		span.SetAttributes(attribute.String("kuberik.failure.reason", failure.Reason))
+		span.SetStatus(codes.Error, failure.Message)
+	}
+	span.End()
+	delete(t.frames, frameID)
+}
+
+// endSceneSpan ends the span of the scene
+func (p *Player) endSceneSpan(play *corev1alpha1.Play, scene string, failed bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	t := p.traces[types.NamespacedName{Namespace: play.Namespace, Name: play.Name}]
+	if t == nil || t.scenes[scene] == nil {
+		return
+	}
+	span := trace.SpanFromContext(t.scenes[scene])
+	if failed {
+		span.SetStatus(codes.Error, "Scene failed")
+	}
+	span.End()
+	delete(t.scenes, scene)
+}
+
+// endPlaySpan sets the final phase on the span of the Play. The span itself is
+// ended when the Play is forgotten. Span of a recovered Play isn't recorded
+// by the Player, so it's left as it is.
+func (p *Player) endPlaySpan(play *corev1alpha1.Play, phase corev1alpha1.PlayPhaseType) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	t := p.playTrace(play)
+	span := trace.SpanFromContext(t.ctx)
+	span.SetAttributes(attribute.String("kuberik.phase", string(phase)))
+	if eventType, _, _ := PhaseEvent(phase); eventType == corev1.EventTypeWarning {
+		span.SetStatus(codes.Error, "Play entered phase "+string(phase))
+	}
+}
+
+// endTrace ends spans of the Play which are still open. Lock must be held.
+func (p *Player) endTrace(key types.NamespacedName) {
+	t, ok := p.traces[key]
+	if !ok {
+		return
+	}
+	for _, span := range t.frames {
+		span.End()
+	}
+	for _, ctx := range t.scenes {
+		trace.SpanFromContext(ctx).End()
+	}
+	trace.SpanFromContext(t.ctx).End()
+	delete(p.traces, key)
+}
+
+// withTraceparent returns a copy of the Exec with the trace context of the span
+// in the context set in environment of all its containers
+func withTraceparent(ctx context.Context, e *corev1alpha1.Exec) *corev1alpha1.Exec {
+	traceparent := tracing.Traceparent(ctx)
+	if traceparent == "" {
+		return e
+	}
+	e = e.DeepCopy()
+	env := corev1.EnvVar{Name: tracing.TraceparentEnv, Value: traceparent}
+	for i := range e.Template.Spec.InitContainers {
+		e.Template.Spec.InitContainers[i].Env = append(e.Template.Spec.InitContainers[i].Env, env)
+	}
+	for i := range e.Template.Spec.Containers {
+		e.Template.Spec.Containers[i].Env = append(e.Template.Spec.Containers[i].Env, env)
+	}
+	return e
+}
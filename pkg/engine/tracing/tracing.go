@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceparentEnv is the environment variable which holds the trace context
+	// of the frame in its containers, in the W3C Trace Context format
+	TraceparentEnv = "TRACEPARENT"
+	// TraceparentAnnotation can be set on a Play to continue the trace of the
+	// caller, such as a CI pipeline, instead of starting a new one. It's set to
+	// the trace context of the Play span once the Play starts.
+	TraceparentAnnotation = "core.kuberik.io/traceparent"
+
+	tracerName  = "github.com/kuberik/kuberik"
+	serviceName = "kuberik"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Tracer returns the tracer of the engine from the global TracerProvider.
+// Spans aren't recorded until a TracerProvider is installed with Init.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Init installs a global TracerProvider which exports spans to the OTLP/HTTP
+// endpoint, such as http://otel-collector:4318. Returned function flushes and
+// stops the exporter.
+func Init(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("OTLP endpoint %s doesn't have a host", endpoint)
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("Unsupported scheme of OTLP endpoint: %s", endpoint)
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return provider.Shutdown, nil
+}
+
+// Traceparent returns the trace context of the span in the context in the
+// W3C Trace Context format, or an empty string if the span isn't recorded
+func Traceparent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ContextWithTraceparent returns the context with the remote span of the
+// traceparent as the parent of new spans. Invalid traceparents are ignored.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+}
@@ -0,0 +1,115 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultTTL is the time for which processed Events are kept if not specified otherwise.
+const DefaultTTL = time.Hour
+
+// PlayFinishedData is the payload of Events published when a Play finishes.
+type PlayFinishedData struct {
+	Play  string                     `json:"play"`
+	Movie string                     `json:"movie,omitempty"`
+	Phase corev1alpha1.PlayPhaseType `json:"phase"`
+	Vars  map[string]string          `json:"vars,omitempty"`
+}
+
+// PlayFinishedType returns the type of the Event published when a Play finishes in the given phase, e.g. PlayComplete.
+func PlayFinishedType(phase corev1alpha1.PlayPhaseType) string {
+	return fmt.Sprintf("Play%s", phase)
+}
+
+// Source returns the source of Events published by a Play. It's the name of the
+// Movie from which the Play was created or name of the Play if it's unknown.
+func Source(play *corev1alpha1.Play) string {
+	if movie := screener.MovieName(play); movie != "" {
+		return movie
+	}
+	return play.Name
+}
+
+// NewPlayFinishedEvent creates an Event announcing that the Play has finished.
+func NewPlayFinishedEvent(play *corev1alpha1.Play) (*corev1alpha1.Event, error) {
+	data := PlayFinishedData{
+		Play:  play.Name,
+		Movie: screener.MovieName(play),
+		Phase: play.Status.Phase,
+		Vars:  make(map[string]string),
+	}
+	for _, v := range play.Spec.Vars {
+		data.Vars[v.Name] = v.Value
+	}
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1alpha1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeutils.Name(play.Name, "finished"),
+			Namespace: play.Namespace,
+		},
+		Spec: corev1alpha1.EventSpec{
+			Type:   PlayFinishedType(play.Status.Phase),
+			Source: Source(play),
+			Data:   string(dataJSON),
+		},
+	}, nil
+}
+
+// NewFrameEvent creates an Event published by a frame of the Play.
+func NewFrameEvent(play *corev1alpha1.Play, frame *corev1alpha1.Frame) *corev1alpha1.Event {
+	return &corev1alpha1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeutils.Name(play.Name, frame.ID),
+			Namespace: play.Namespace,
+		},
+		Spec: corev1alpha1.EventSpec{
+			Type:   frame.Publish.Type,
+			Source: Source(play),
+			Data:   expandVars(frame.Publish.Data, play.Spec.Vars),
+		},
+	}
+}
+
+// Publish creates the Event. Events have deterministic names, so publishing
+// the same Event again is a no-op.
+func Publish(c client.Client, event *corev1alpha1.Event) error {
+	err := c.Create(context.TODO(), event)
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// Expired checks if a processed Event outlived its TTL and returns the time left otherwise.
+func Expired(event *corev1alpha1.Event, now time.Time) (bool, time.Duration) {
+	if event.Status.ProcessedTime == nil {
+		return false, 0
+	}
+	ttl := DefaultTTL
+	if event.Spec.TTLSecondsAfterProcessed != nil {
+		ttl = time.Duration(*event.Spec.TTLSecondsAfterProcessed) * time.Second
+	}
+	left := event.Status.ProcessedTime.Add(ttl).Sub(now)
+	return left <= 0, left
+}
+
+func expandVars(s string, vars corev1alpha1.Vars) string {
+	var replacements []string
+	for _, v := range vars {
+		replacements = append(replacements, fmt.Sprintf("$(%s)", v.Name), v.Value)
+	}
+	return strings.NewReplacer(replacements...).Replace(s)
+}
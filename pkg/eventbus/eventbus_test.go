@@ -0,0 +1,88 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPublish(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(scheme)
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", Labels: map[string]string{screener.MovieLabel: "app"}},
+		Spec:       corev1alpha1.PlaySpec{Vars: corev1alpha1.Vars{{Name: "VERSION", Value: "1.0"}}},
+		Status:     corev1alpha1.PlayStatus{Phase: corev1alpha1.PlayComplete},
+	}
+	event, err := NewPlayFinishedEvent(play)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Publish(c, event); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := NewPlayFinishedEvent(play)
+	if err := Publish(c, again); err != nil {
+		t.Errorf("Expected publishing the Event again to be a no-op, got %s", err)
+	}
+
+	published := &corev1alpha1.Event{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: kubeutils.Name("app-1", "finished")}, published); err != nil {
+		t.Fatal(err)
+	}
+	if published.Spec.Type != "PlayComplete" || published.Spec.Source != "app" {
+		t.Errorf("Expected PlayComplete Event from app, got %s from %s", published.Spec.Type, published.Spec.Source)
+	}
+	data := PlayFinishedData{}
+	if err := json.Unmarshal([]byte(published.Spec.Data), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Play != "app-1" || data.Vars["VERSION"] != "1.0" {
+		t.Errorf("Expected data of Play app-1 with its vars, got %+v", data)
+	}
+}
+
+func TestNewFrameEvent(t *testing.T) {
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default"},
+		Spec:       corev1alpha1.PlaySpec{Vars: corev1alpha1.Vars{{Name: "VERSION", Value: "1.0"}}},
+	}
+	frame := &corev1alpha1.Frame{ID: "abc", Publish: &corev1alpha1.EventPublish{Type: "Released", Data: `{"version": "$(VERSION)"}`}}
+	event := NewFrameEvent(play, frame)
+	if event.Name != kubeutils.Name("app-1", "abc") || event.Spec.Source != "app-1" || event.Spec.Data != `{"version": "1.0"}` {
+		t.Errorf("Expected Event of the frame with expanded vars, got %+v", event)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	processed := metav1.NewTime(now.Add(-time.Minute))
+	ttl := int32(120)
+	event := &corev1alpha1.Event{}
+	if expired, _ := Expired(event, now); expired {
+		t.Error("Expected Event which wasn't processed not to expire")
+	}
+	event.Status.ProcessedTime = &processed
+	if expired, _ := Expired(event, now); expired {
+		t.Error("Expected Event not to expire before the default TTL")
+	}
+	event.Spec.TTLSecondsAfterProcessed = &ttl
+	if expired, left := Expired(event, now); expired || left != time.Minute {
+		t.Errorf("Expected Event to expire in a minute, got %s", left)
+	}
+	if expired, _ := Expired(event, now.Add(time.Hour)); !expired {
+		t.Error("Expected Event to expire after its TTL")
+	}
+}
@@ -0,0 +1,20 @@
+package kubeutils
+
+import (
+	"regexp"
+	"strings"
+)
+
+const maxLabelValueLength = 63
+
+var invalidLabelValueChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// LabelValue converts the value to a valid label value. Invalid characters are
+// replaced with '-' and the value is truncated to the maximum length of labels.
+func LabelValue(value string) string {
+	value = invalidLabelValueChars.ReplaceAllString(value, "-")
+	if len(value) > maxLabelValueLength {
+		value = value[:maxLabelValueLength]
+	}
+	return strings.Trim(value, "-_.")
+}
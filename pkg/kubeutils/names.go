@@ -0,0 +1,35 @@
+package kubeutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+const (
+	// length of readable prefixes of names, leaving room for the hash and for
+	// names of objects which are derived from the name
+	maxNamePrefixLength = 40
+	nameHashLength      = 10
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Name returns a name of an object built from the parts. Name is a readable
+// prefix of the parts, cut to leave room for names derived from it, followed by
+// a hash of all parts, so it's a valid DNS label which is the same for the
+// same parts and differs for parts which share the prefix.
+func Name(parts ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(parts, "/")))
+	prefix := invalidNameChars.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "-")
+	if len(prefix) > maxNamePrefixLength {
+		prefix = prefix[:maxNamePrefixLength]
+	}
+	prefix = strings.Trim(prefix, "-")
+	name := hex.EncodeToString(hash[:])[:nameHashLength]
+	if prefix != "" {
+		name = prefix + "-" + name
+	}
+	return name
+}
@@ -0,0 +1,29 @@
+package kubeutils
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestName(t *testing.T) {
+	long := strings.Repeat("a", 253)
+	for _, parts := range [][]string{
+		{"app-1", "finished"},
+		{long, "finished"},
+		{"Master", "refs/heads/feature_X"},
+		{"--", ""},
+	} {
+		name := Name(parts...)
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			t.Errorf("Expected valid name of %v, got %s: %v", parts, name, errs)
+		}
+	}
+	if Name(long, "a") == Name(long, "b") {
+		t.Error("Expected names of parts sharing a prefix to differ")
+	}
+	if !strings.HasPrefix(Name("app-1", "finished"), "app-1-finished-") {
+		t.Errorf("Expected name to start with its parts, got %s", Name("app-1", "finished"))
+	}
+}
@@ -0,0 +1,154 @@
+// Package playtree builds the tree of screenplays, scenes and frames of a Play
+// with their statuses. It's shared by the API, the dashboard and the CLI.
+package playtree
+
+import (
+	"fmt"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/screener"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Status is the status of a scene or a frame in a Tree
+type Status string
+
+// Statuses of scenes and frames
+const (
+	StatusPending   Status = "Pending"
+	StatusRunning   Status = "Running"
+	StatusSucceeded Status = "Succeeded"
+	StatusFailed    Status = "Failed"
+	// StatusSkipped means the Play finished before the frame ran
+	StatusSkipped Status = "Skipped"
+)
+
+// Tree is the status of a Play with its screenplays, scenes and frames
+type Tree struct {
+	Name           string                       `json:"name"`
+	Namespace      string                       `json:"namespace"`
+	Movie          string                       `json:"movie,omitempty"`
+	Phase          corev1alpha1.PlayPhaseType   `json:"phase,omitempty"`
+	Cancelled      bool                         `json:"cancelled,omitempty"`
+	StartTime      *metav1.Time                 `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time                 `json:"completionTime,omitempty"`
+	Conditions     []corev1alpha1.PlayCondition `json:"conditions,omitempty"`
+	Screenplays    []Screenplay                 `json:"screenplays"`
+}
+
+// Screenplay is a screenplay of a Tree
+type Screenplay struct {
+	Name   string  `json:"name"`
+	Scenes []Scene `json:"scenes"`
+}
+
+// Scene is a scene of a Tree
+type Scene struct {
+	Name         string  `json:"name"`
+	Status       Status  `json:"status"`
+	IgnoreErrors bool    `json:"ignoreErrors,omitempty"`
+	Frames       []Frame `json:"frames"`
+}
+
+// Frame is a frame of a Tree. Copies of frames are listed as separate frames.
+type Frame struct {
+	ID       string                     `json:"id"`
+	Name     string                     `json:"name"`
+	Status   Status                     `json:"status"`
+	ExitCode *int                       `json:"exitCode,omitempty"`
+	Failure  *corev1alpha1.FrameFailure `json:"failure,omitempty"`
+}
+
+// New builds the tree of the Play from its spec and status. Frames of
+// a scene run once all previous scenes succeeded, so frames of the first
+// unfinished scene of a running Play are running and the following are pending.
+func New(play *corev1alpha1.Play) Tree {
+	tree := Tree{
+		Name:           play.Name,
+		Namespace:      play.Namespace,
+		Movie:          screener.MovieName(play),
+		Phase:          play.Status.Phase,
+		Cancelled:      play.Spec.Cancel,
+		StartTime:      play.Status.StartTime,
+		CompletionTime: play.Status.CompletionTime,
+		Conditions:     play.Status.Conditions,
+		Screenplays:    []Screenplay{},
+	}
+	finished := play.Status.Phase == corev1alpha1.PlayComplete || play.Status.Phase == corev1alpha1.PlayFailed || play.Status.Phase == corev1alpha1.PlayError
+	for _, screenplay := range play.Spec.Screenplays {
+		screenplayTree := Screenplay{Name: screenplay.Name, Scenes: []Scene{}}
+		blocked := false
+		for _, scene := range screenplay.Scenes {
+			sceneTree := Scene{Name: scene.Name, IgnoreErrors: scene.IgnoreErrors, Frames: []Frame{}}
+			unfinished, failed := false, false
+			for _, frame := range frameCopies(scene.Frames) {
+				frameTree := Frame{ID: frame.ID, Name: frame.Name}
+				exit, recorded := play.Status.Frames[frame.ID]
+				switch {
+				case recorded:
+					frameTree.ExitCode = &exit
+					if failure, ok := play.Status.FrameFailures[frame.ID]; ok {
+						frameTree.Failure = &failure
+					}
+					frameTree.Status = StatusSucceeded
+					if exit != 0 {
+						frameTree.Status = StatusFailed
+						failed = true
+					}
+				case finished:
+					frameTree.Status = StatusSkipped
+				case blocked || play.Status.Phase != corev1alpha1.PlayRunning:
+					frameTree.Status = StatusPending
+					unfinished = true
+				default:
+					frameTree.Status = StatusRunning
+					unfinished = true
+				}
+				sceneTree.Frames = append(sceneTree.Frames, frameTree)
+			}
+			sceneTree.Status = sceneStatus(sceneTree.Frames, scene.IgnoreErrors)
+			blocked = blocked || unfinished || (failed && !scene.IgnoreErrors)
+			screenplayTree.Scenes = append(screenplayTree.Scenes, sceneTree)
+		}
+		tree.Screenplays = append(tree.Screenplays, screenplayTree)
+	}
+	return tree
+}
+
+// sceneStatus aggregates statuses of frames of a scene
+func sceneStatus(frames []Frame, ignoreErrors bool) Status {
+	counts := make(map[Status]int)
+	for _, f := range frames {
+		counts[f.Status]++
+	}
+	switch {
+	case counts[StatusFailed] > 0 && !ignoreErrors:
+		return StatusFailed
+	case counts[StatusRunning] > 0:
+		return StatusRunning
+	case counts[StatusSucceeded]+counts[StatusFailed] == len(frames):
+		return StatusSucceeded
+	case counts[StatusPending] == len(frames):
+		return StatusPending
+	}
+	// Play finished before all frames of the scene ran
+	return StatusSkipped
+}
+
+// frameCopies expands copies of frames the same way the Player does
+func frameCopies(frames []corev1alpha1.Frame) []corev1alpha1.Frame {
+	var expanded []corev1alpha1.Frame
+	for _, f := range frames {
+		if f.Copies <= 1 {
+			expanded = append(expanded, f)
+			continue
+		}
+		for i := 0; i < f.Copies; i++ {
+			expanded = append(expanded, corev1alpha1.Frame{
+				ID:   fmt.Sprintf("%s-%v", f.ID, i),
+				Name: fmt.Sprintf("%s-%v", f.Name, i),
+			})
+		}
+	}
+	return expanded
+}
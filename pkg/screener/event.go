@@ -0,0 +1,43 @@
+package screener
+
+import (
+	"path"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+)
+
+// Vars populated in Plays created by an event screener.
+const (
+	EventTypeVar   = "EVENT_TYPE"
+	EventSourceVar = "EVENT_SOURCE"
+	EventNameVar   = "EVENT_NAME"
+)
+
+// EventVars returns Play vars describing the Event.
+func EventVars(event *corev1alpha1.Event) corev1alpha1.Vars {
+	return corev1alpha1.Vars{
+		{Name: EventTypeVar, Value: event.Spec.Type},
+		{Name: EventSourceVar, Value: event.Spec.Source},
+		{Name: EventNameVar, Value: event.Name},
+	}
+}
+
+// MatchEvent checks if the screener subscribes to the Event.
+func MatchEvent(spec *corev1alpha1.EventScreener, event *corev1alpha1.Event) bool {
+	if spec == nil {
+		return false
+	}
+	return matchAny(spec.Types, event.Spec.Type) && matchAny(spec.Sources, event.Spec.Source)
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package screener
+
+import (
+	"testing"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+)
+
+func TestMatchEvent(t *testing.T) {
+	event := &corev1alpha1.Event{Spec: corev1alpha1.EventSpec{Type: "PlayComplete", Source: "build"}}
+	for _, test := range []struct {
+		spec    *corev1alpha1.EventScreener
+		matched bool
+	}{
+		{nil, false},
+		{&corev1alpha1.EventScreener{}, true},
+		{&corev1alpha1.EventScreener{Types: []string{"PlayComplete"}}, true},
+		{&corev1alpha1.EventScreener{Types: []string{"Play*"}, Sources: []string{"build"}}, true},
+		{&corev1alpha1.EventScreener{Types: []string{"PlayFailed", "PlayError"}}, false},
+		{&corev1alpha1.EventScreener{Types: []string{"Play*"}, Sources: []string{"deploy"}}, false},
+	} {
+		if matched := MatchEvent(test.spec, event); matched != test.matched {
+			t.Errorf("Expected screener %+v to match %t, got %t", test.spec, test.matched, matched)
+		}
+	}
+}
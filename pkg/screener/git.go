@@ -0,0 +1,145 @@
+package screener
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+)
+
+// Vars populated in Plays created by a git screener.
+const (
+	GitCommitVar = "GIT_COMMIT"
+	GitRefVar    = "GIT_REF"
+	GitAuthorVar = "GIT_AUTHOR"
+)
+
+const (
+	branchPrefix = "refs/heads/"
+	tagPrefix    = "refs/tags/"
+)
+
+// GitRevision is a commit seen on a ref of a git remote.
+type GitRevision struct {
+	Ref    string
+	Commit string
+	Author string
+}
+
+// Vars returns Play vars describing the revision.
+func (r GitRevision) Vars() corev1alpha1.Vars {
+	return corev1alpha1.Vars{
+		{Name: GitCommitVar, Value: r.Commit},
+		{Name: GitRefVar, Value: r.Ref},
+		{Name: GitAuthorVar, Value: r.Author},
+	}
+}
+
+// GitPoller polls a git remote by fetching its refs into a local bare repository.
+type GitPoller struct {
+	// Dir is the path of the local repository. It's created on the first poll.
+	Dir string
+}
+
+// Poll fetches the remote and returns the revisions of all refs matching the screener.
+func (p *GitPoller) Poll(spec *corev1alpha1.GitScreener) ([]GitRevision, error) {
+	if _, err := os.Stat(filepath.Join(p.Dir, "HEAD")); os.IsNotExist(err) {
+		if err := os.MkdirAll(p.Dir, 0755); err != nil {
+			return nil, err
+		}
+		if _, err := p.git("init", "--bare"); err != nil {
+			return nil, err
+		}
+	}
+
+	branches, tags := spec.Branches, spec.Tags
+	if len(branches) == 0 && len(tags) == 0 {
+		branches = []string{"*"}
+	}
+
+	args := []string{"fetch", "--prune", "--no-tags", spec.Repository}
+	if len(branches) > 0 {
+		args = append(args, fmt.Sprintf("+%s*:%s*", branchPrefix, branchPrefix))
+	}
+	if len(tags) > 0 {
+		args = append(args, fmt.Sprintf("+%s*:%s*", tagPrefix, tagPrefix))
+	}
+	if _, err := p.git(args...); err != nil {
+		return nil, err
+	}
+
+	// Annotated tags point to tag objects, so peeled fields (prefixed with *)
+	// are used to get to the commit.
+	out, err := p.git(
+		"for-each-ref",
+		"--format=%(refname)\t%(objectname)\t%(authorname) %(authoremail)\t%(*objectname)\t%(*authorname) %(*authoremail)",
+		strings.TrimSuffix(branchPrefix, "/"), strings.TrimSuffix(tagPrefix, "/"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []GitRevision
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		revision := GitRevision{Ref: fields[0], Commit: fields[1], Author: fields[2]}
+		if fields[3] != "" {
+			revision.Commit, revision.Author = fields[3], fields[4]
+		}
+		if matchRef(revision.Ref, branchPrefix, branches) || matchRef(revision.Ref, tagPrefix, tags) {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+func (p *GitPoller) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = p.Dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+func matchRef(ref, prefix string, patterns []string) bool {
+	if !strings.HasPrefix(ref, prefix) {
+		return false
+	}
+	name := strings.TrimPrefix(ref, prefix)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangedRevisions returns revisions which are new or point to a different
+// commit than the one previously seen.
+func ChangedRevisions(seen map[string]string, revisions []GitRevision) (changed []GitRevision) {
+	for _, r := range revisions {
+		if commit, ok := seen[r.Ref]; !ok || commit != r.Commit {
+			changed = append(changed, r)
+		}
+	}
+	return
+}
+
+// RevisionMap maps refs of revisions to their commits.
+func RevisionMap(revisions []GitRevision) map[string]string {
+	seen := make(map[string]string)
+	for _, r := range revisions {
+		seen[r.Ref] = r.Commit
+	}
+	return seen
+}
@@ -0,0 +1,89 @@
+package screener
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Dave", "-c", "user.email=dave@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitPoll(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "kuberik-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	remote := filepath.Join(dir, "remote.git")
+	work := filepath.Join(dir, "work")
+	os.Mkdir(remote, 0755)
+	os.Mkdir(work, 0755)
+	git(t, remote, "init", "--bare")
+	git(t, work, "init")
+	git(t, work, "checkout", "-b", "master")
+	git(t, work, "commit", "--allow-empty", "-m", "first")
+	git(t, work, "tag", "-a", "v1.0.0", "-m", "release")
+	git(t, work, "checkout", "-b", "feature")
+	git(t, work, "commit", "--allow-empty", "-m", "feature")
+	git(t, work, "push", remote, "master", "feature", "v1.0.0")
+
+	poller := &GitPoller{Dir: filepath.Join(dir, "cache")}
+	spec := &corev1alpha1.GitScreener{
+		Repository: remote,
+		Branches:   []string{"master"},
+		Tags:       []string{"v*"},
+	}
+	revisions, err := poller.Poll(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := RevisionMap(revisions)
+	if len(seen) != 2 {
+		t.Fatalf("Expected master and v1.0.0 refs, got %v", seen)
+	}
+	master := git(t, work, "rev-parse", "master")
+	if seen["refs/heads/master"] != master {
+		t.Errorf("Wrong commit for master: %s", seen["refs/heads/master"])
+	}
+	if seen["refs/tags/v1.0.0"] != master {
+		t.Errorf("Annotated tag isn't peeled to a commit: %s", seen["refs/tags/v1.0.0"])
+	}
+	for _, r := range revisions {
+		if r.Author != "Dave <dave@example.com>" {
+			t.Errorf("Wrong author of %s: %s", r.Ref, r.Author)
+		}
+	}
+
+	git(t, work, "checkout", "master")
+	git(t, work, "commit", "--allow-empty", "-m", "second")
+	git(t, work, "push", remote, "master")
+
+	revisions, err = poller.Poll(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := ChangedRevisions(seen, revisions)
+	if len(changed) != 1 || changed[0].Ref != "refs/heads/master" {
+		t.Fatalf("Expected only master to change, got %v", changed)
+	}
+	if changed[0].Commit != git(t, work, "rev-parse", "master") {
+		t.Errorf("Changed revision doesn't point to the new commit")
+	}
+}
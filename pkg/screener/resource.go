@@ -0,0 +1,52 @@
+package screener
+
+import (
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Vars populated in Plays created by a resource screener.
+const (
+	ResourceEventVar     = "RESOURCE_EVENT"
+	ResourceNameVar      = "RESOURCE_NAME"
+	ResourceNamespaceVar = "RESOURCE_NAMESPACE"
+)
+
+// ResourceEvent is a change of a resource watched by a screener.
+type ResourceEvent struct {
+	Type      corev1alpha1.ResourceEventType
+	Name      string
+	Namespace string
+	// Object is the JSON representation of the resource at the time of the event.
+	Object []byte
+}
+
+// Vars returns Play vars describing the event.
+func (e ResourceEvent) Vars() corev1alpha1.Vars {
+	return corev1alpha1.Vars{
+		{Name: ResourceEventVar, Value: string(e.Type)},
+		{Name: ResourceNameVar, Value: e.Name},
+		{Name: ResourceNamespaceVar, Value: e.Namespace},
+	}
+}
+
+// ResourceGVK returns the kind watched by a resource screener.
+func ResourceGVK(spec *corev1alpha1.ResourceScreener) schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(spec.APIVersion, spec.Kind)
+}
+
+// MatchResourceEvent checks if the screener should trigger a Play for an event on a resource of the given kind.
+func MatchResourceEvent(spec *corev1alpha1.ResourceScreener, gvk schema.GroupVersionKind, eventType corev1alpha1.ResourceEventType) bool {
+	if spec == nil || ResourceGVK(spec) != gvk {
+		return false
+	}
+	if len(spec.Events) == 0 {
+		return true
+	}
+	for _, e := range spec.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
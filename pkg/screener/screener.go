@@ -0,0 +1,83 @@
+package screener
+
+import (
+	"fmt"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	"github.com/kuberik/kuberik/pkg/kubeutils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MovieLabel is the label holding the name of the Movie a Play was created
+	// from, converted to a label value
+	MovieLabel = "core.kuberik.io/movie"
+	// ScreenerLabel is the label holding the name of the Screener which created
+	// a Play, converted to a label value
+	ScreenerLabel = "core.kuberik.io/screener"
+	// MovieAnnotation is the annotation holding the full name of the Movie a
+	// Play was created from
+	MovieAnnotation = "core.kuberik.io/movie"
+	// ScreenerAnnotation is the annotation holding the full name of the
+	// Screener which created a Play
+	ScreenerAnnotation = "core.kuberik.io/screener"
+	// UpstreamAnnotation holds the name of the Play which triggered a Play
+	UpstreamAnnotation = "core.kuberik.io/upstream"
+	// DownstreamAnnotation holds a comma separated list of Plays triggered by a Play
+	DownstreamAnnotation = "core.kuberik.io/downstream"
+	// TriggerAnnotation holds what created a Play which wasn't created by a
+	// Screener, a Movie trigger or an operator Movie, such as api
+	TriggerAnnotation = "core.kuberik.io/trigger"
+)
+
+// MovieSelector returns labels selecting Plays of the Movie. Names which are
+// not valid label values can select Plays of other Movies, so the selected
+// Plays need to be checked with MovieName.
+func MovieSelector(movie string) map[string]string {
+	return map[string]string{MovieLabel: kubeutils.LabelValue(movie)}
+}
+
+// MovieName returns the name of the Movie the Play was created from. Plays
+// without the annotation fall back to the label.
+func MovieName(play *corev1alpha1.Play) string {
+	if movie, ok := play.Annotations[MovieAnnotation]; ok {
+		return movie
+	}
+	return play.Labels[MovieLabel]
+}
+
+// ScreenerName returns the name of the Screener which created the Play. Plays
+// without the annotation fall back to the label.
+func ScreenerName(play *corev1alpha1.Play) string {
+	if screener, ok := play.Annotations[ScreenerAnnotation]; ok {
+		return screener
+	}
+	return play.Labels[ScreenerLabel]
+}
+
+// NewPlay creates a Play from the template of a Movie. Vars which are not
+// declared by the Movie are appended to the Play vars. Screener is the name of
+// the Screener creating the Play and can be empty.
+func NewPlay(movie *corev1alpha1.Movie, screener string, vars corev1alpha1.Vars) *corev1alpha1.Play {
+	play := &corev1alpha1.Play{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", movie.Name),
+			Namespace:    movie.Namespace,
+			Labels:       MovieSelector(movie.Name),
+			Annotations: map[string]string{
+				MovieAnnotation: movie.Name,
+			},
+		},
+		Spec: *movie.Spec.Template.Spec.DeepCopy(),
+	}
+	if screener != "" {
+		play.Labels[ScreenerLabel] = kubeutils.LabelValue(screener)
+		play.Annotations[ScreenerAnnotation] = screener
+	}
+	for _, v := range vars {
+		if err := play.Spec.Vars.Set(v.Name, v.Value); err != nil {
+			play.Spec.Vars = append(play.Spec.Vars, v)
+		}
+	}
+	return play
+}
@@ -0,0 +1,25 @@
+package screener
+
+import (
+	"strings"
+	"testing"
+
+	corev1alpha1 "github.com/kuberik/kuberik/pkg/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestNewPlay(t *testing.T) {
+	movie := &corev1alpha1.Movie{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("movie.", 20) + "app"}}
+	screener := strings.Repeat("screener.", 10) + "master"
+	play := NewPlay(movie, screener, nil)
+
+	for _, label := range []string{MovieLabel, ScreenerLabel} {
+		if errs := validation.IsValidLabelValue(play.Labels[label]); len(errs) > 0 {
+			t.Errorf("Expected valid value of label %s, got %v", label, errs)
+		}
+	}
+	if MovieName(play) != movie.Name || ScreenerName(play) != screener {
+		t.Errorf("Expected full names of the Movie and the Screener, got %q and %q", MovieName(play), ScreenerName(play))
+	}
+}